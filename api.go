@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+)
+
+// apiStore is the part of *database.Queries used by the API handlers and the
+// command logic they share, so the API can be tested without Postgres.
+type apiStore interface {
+	GetUser(ctx context.Context, name string) (database.User, error)
+	GetUserBySessionToken(ctx context.Context, tokenHash string) (database.User, error)
+	ListUsersPage(ctx context.Context, arg database.ListUsersPageParams) ([]database.User, error)
+	CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error)
+	GetFeedByURL(ctx context.Context, url string) (database.Feed, error)
+	ListFeedsPage(ctx context.Context, arg database.ListFeedsPageParams) ([]database.Feed, error)
+	CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error)
+	DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error)
+	GetPostDetails(ctx context.Context, id uuid.UUID) (database.GetPostDetailsRow, error)
+	GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error)
+	MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error
+	StarPost(ctx context.Context, arg database.StarPostParams) error
+	UnstarPost(ctx context.Context, arg database.UnstarPostParams) error
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiPage[T any] struct {
+	Items      []T  `json:"items"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	NextOffset *int `json:"next_offset"`
+}
+
+type apiSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID     `json:"id"`
+	Name          string        `json:"name"`
+	URL           string        `json:"url"`
+	UserID        uuid.NullUUID `json:"user_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	LastFetchedAt *time.Time    `json:"last_fetched_at"`
+	ParseWarnings []string      `json:"parse_warnings"`
+}
+
+type apiFollow struct {
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedURL   string    `json:"feed_url"`
+	Tags      []string  `json:"tags"`
+	Priority  int32     `json:"priority"`
+	Muted     bool      `json:"muted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Summary     string     `json:"summary"`
+	Author      string     `json:"author"`
+	Categories  []string   `json:"categories"`
+	PublishedAt *time.Time `json:"published_at"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Updated     bool       `json:"updated"`
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func apiFeedFrom(feed database.Feed) apiFeed {
+	return apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		URL:           feed.Url,
+		UserID:        feed.UserID,
+		CreatedAt:     feed.CreatedAt,
+		LastFetchedAt: nullTimePtr(feed.LastFetchedAt),
+		ParseWarnings: feed.ParseWarnings,
+	}
+}
+
+func (srv *server) apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/sessions", srv.handleAPILogin)
+	mux.HandleFunc("DELETE /api/v1/sessions", srv.apiUser(srv.handleAPILogout))
+	mux.HandleFunc("GET /api/v1/users", srv.apiUser(srv.handleAPIUsers))
+	mux.HandleFunc("GET /api/v1/feeds", srv.apiUser(srv.handleAPIFeeds))
+	mux.HandleFunc("POST /api/v1/feeds", srv.apiUser(srv.handleAPIAddFeed))
+	mux.HandleFunc("GET /api/v1/follows", srv.apiUser(srv.handleAPIFollows))
+	mux.HandleFunc("POST /api/v1/follows", srv.apiUser(srv.handleAPIFollow))
+	mux.HandleFunc("DELETE /api/v1/follows", srv.apiUser(srv.handleAPIUnfollow))
+	mux.HandleFunc("GET /api/v1/posts", srv.apiUser(srv.handleAPIPosts))
+	mux.HandleFunc("POST /api/v1/posts/{id}/{action}", srv.apiUser(srv.handleAPIPostAction))
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not found")
+	})
+}
+
+// apiUser is the API counterpart of middlewareLoggedIn: the acting user is
+// identified by the session token sent as "Authorization: Bearer <token>".
+func (srv *server) apiUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			writeAPIError(w, http.StatusUnauthorized, "a bearer token is required")
+			return
+		}
+		user, err := sessionUser(r.Context(), srv.store, token)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
+
+// writeAPIErr maps an error returned by the shared command logic to an HTTP
+// status. The error text can name tables and constraints, so it is logged
+// rather than sent to the client.
+func writeAPIErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeAPIError(w, http.StatusNotFound, "not found")
+	case database.IsUniqueViolation(err):
+		writeAPIError(w, http.StatusConflict, "already exists")
+	default:
+		fmt.Printf("api: %v\n", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// pagination reads the limit and offset query parameters.
+func pagination(r *http.Request) (limit, offset int, err error) {
+	limit = apiDefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > apiMaxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", apiMaxLimit)
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// newAPIPage wraps one page of results. A full page may be followed by more
+// results, so it links to the next one.
+func newAPIPage[T any](items []T, limit, offset int) apiPage[T] {
+	page := apiPage[T]{Items: items, Limit: limit, Offset: offset}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(items) == limit {
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	return page
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func (srv *server) handleAPILogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	user, err := srv.store.GetUser(ctx, body.Name)
+	if err == nil {
+		err = authenticate(user, body.Password)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusUnauthorized, "invalid user name or password")
+		return
+	}
+
+	token, session, err := createSession(ctx, srv.store, user)
+	if err != nil {
+		writeAPIErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiSession{Token: token, ExpiresAt: session.ExpiresAt})
+}
+
+func (srv *server) handleAPILogout(w http.ResponseWriter, r *http.Request, user database.User) {
+	token, _ := bearerToken(r)
+	if err := srv.store.DeleteSession(r.Context(), auth.HashToken(token)); err != nil {
+		writeAPIErr(w, fmt.Errorf("failed to delete session: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *server) handleAPIUsers(w http.ResponseWriter, r *http.Request, _ database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := srv.store.ListUsersPage(r.Context(), database.ListUsersPageParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		writeAPIErr(w, fmt.Errorf("failed to list users: %w", err))
+		return
+	}
+
+	items := make([]apiUser, 0, len(users))
+	for _, user := range users {
+		items = append(items, apiUser{ID: user.ID, Name: user.Name, Role: user.Role, CreatedAt: user.CreatedAt})
+	}
+	writeJSON(w, http.StatusOK, newAPIPage(items, limit, offset))
+}
+
+func (srv *server) handleAPIFeeds(w http.ResponseWriter, r *http.Request, _ database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	feeds, err := srv.store.ListFeedsPage(r.Context(), database.ListFeedsPageParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		writeAPIErr(w, fmt.Errorf("failed to list feeds: %w", err))
+		return
+	}
+
+	items := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		items = append(items, apiFeedFrom(feed))
+	}
+	writeJSON(w, http.StatusOK, newAPIPage(items, limit, offset))
+}
+
+func (srv *server) handleAPIAddFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+
+	feed, _, err := addFeed(r.Context(), srv.store, user, body.Name, body.URL)
+	if err != nil {
+		writeAPIErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFeedFrom(feed))
+}
+
+func (srv *server) handleAPIFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	follows, err := srv.store.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		writeAPIErr(w, fmt.Errorf("failed to get feed follows: %w", err))
+		return
+	}
+
+	// A user follows few enough feeds that they are paged in memory.
+	follows = follows[min(offset, len(follows)):]
+	follows = follows[:min(limit, len(follows))]
+
+	items := make([]apiFollow, 0, len(follows))
+	for _, follow := range follows {
+		items = append(items, apiFollow{
+			ID:        follow.ID,
+			FeedID:    follow.FeedID,
+			FeedName:  follow.FeedName,
+			FeedURL:   follow.FeedUrl,
+			Tags:      follow.Tags,
+			Priority:  follow.Priority,
+			Muted:     follow.Muted,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, newAPIPage(items, limit, offset))
+}
+
+func (srv *server) handleAPIFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		FeedURL string `json:"feed_url"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	follow, err := followFeed(r.Context(), srv.store, user, body.FeedURL)
+	if err != nil {
+		writeAPIErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFollow{
+		ID:        follow.ID,
+		FeedID:    follow.FeedID,
+		FeedName:  follow.FeedName,
+		FeedURL:   body.FeedURL,
+		Tags:      follow.Tags,
+		CreatedAt: follow.CreatedAt,
+	})
+}
+
+func (srv *server) handleAPIUnfollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedURL := r.URL.Query().Get("feed_url")
+	if feedURL == "" {
+		writeAPIError(w, http.StatusBadRequest, "feed_url is required")
+		return
+	}
+
+	if _, err := unfollowFeed(r.Context(), srv.store, user, feedURL); err != nil {
+		writeAPIErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *server) handleAPIPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	var unreadOnly bool
+	if v := query.Get("unread"); v != "" {
+		unreadOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "unread must be true or false")
+			return
+		}
+	}
+	applyFilters := true
+	if v := query.Get("filtered"); v != "" {
+		applyFilters, err = strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "filtered must be true or false")
+			return
+		}
+	}
+	var feedID uuid.NullUUID
+	if v := query.Get("feed"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "feed must be a feed ID")
+			return
+		}
+		feedID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	posts, err := srv.store.GetPostsForUser(r.Context(), database.GetPostsForUserParams{
+		UserID:       user.ID,
+		UnreadOnly:   unreadOnly,
+		FeedID:       feedID,
+		Tag:          sql.NullString{String: query.Get("tag"), Valid: query.Get("tag") != ""},
+		ApplyFilters: applyFilters,
+		Limit:        int32(limit),
+		Offset:       int32(offset),
+	})
+	if err != nil {
+		writeAPIErr(w, fmt.Errorf("failed to get posts: %w", err))
+		return
+	}
+
+	items := make([]apiPost, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, apiPost{
+			ID:          post.ID,
+			FeedID:      post.FeedID,
+			FeedName:    post.FeedName,
+			Title:       post.Title,
+			URL:         post.Url,
+			Summary:     post.Summary.String,
+			Author:      post.Author.String,
+			Categories:  post.Categories,
+			PublishedAt: nullTimePtr(post.PublishedAt),
+			Read:        post.Read,
+			Starred:     post.Starred,
+			Updated:     post.Updated,
+		})
+	}
+	writeJSON(w, http.StatusOK, newAPIPage(items, limit, offset))
+}
+
+func (srv *server) handleAPIPostAction(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid post ID")
+		return
+	}
+
+	ctx := r.Context()
+	switch r.PathValue("action") {
+	case "read":
+		err = setPostRead(ctx, srv.store, user, postID, true)
+	case "unread":
+		err = setPostRead(ctx, srv.store, user, postID, false)
+	case "star":
+		err = setPostStarred(ctx, srv.store, user, postID, true)
+	case "unstar":
+		err = setPostStarred(ctx, srv.store, user, postID, false)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeAPIErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
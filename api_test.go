@@ -0,0 +1,230 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// newTestAPI serves the API from an in-memory store holding one user, alice,
+// with the password "hunter22" and a session token.
+func newTestAPI(t *testing.T) (*httptest.Server, *memStore, string) {
+	t.Helper()
+	hash, err := auth.HashPassword("hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newMemStore()
+	alice := database.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		Name:         "alice",
+		PasswordHash: sql.NullString{String: hash, Valid: true},
+		Role:         "user",
+	}
+	store.users[alice.Name] = alice
+	token := "test-token"
+	store.sessions[auth.HashToken(token)] = alice.ID
+
+	srv := &server{store: store}
+	mux := http.NewServeMux()
+	srv.apiRoutes(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, store, token
+}
+
+func apiRequest(t *testing.T, ts *httptest.Server, method, path, token, body string) (*http.Response, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var decoded map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	ts, _, _ := newTestAPI(t)
+	for _, token := range []string{"", "wrong"} {
+		resp, body := apiRequest(t, ts, "GET", "/api/v1/posts", token, "")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, resp.StatusCode)
+		}
+		if body["error"] == "" {
+			t.Errorf("token %q: no error message", token)
+		}
+	}
+}
+
+func TestAPILogin(t *testing.T) {
+	ts, _, _ := newTestAPI(t)
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"name":"alice","password":"hunter22"}`, http.StatusCreated},
+		{`{"name":"alice","password":"wrong"}`, http.StatusUnauthorized},
+		{`{"name":"bob","password":"hunter22"}`, http.StatusUnauthorized},
+		{`{"name":"alice","pass":"hunter22"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp, body := apiRequest(t, ts, "POST", "/api/v1/sessions", "", tt.body)
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.body, resp.StatusCode, tt.want)
+			continue
+		}
+		if tt.want != http.StatusCreated {
+			continue
+		}
+		token, _ := body["token"].(string)
+		resp, _ = apiRequest(t, ts, "GET", "/api/v1/users", token, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("new session: status = %d, want 200", resp.StatusCode)
+		}
+	}
+}
+
+func TestAPIFeedsAndFollows(t *testing.T) {
+	ts, _, token := newTestAPI(t)
+
+	resp, _ := apiRequest(t, ts, "POST", "/api/v1/feeds", token, `{"name":"Blog","url":"https://example.com/feed"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("add feed: status = %d, want 201", resp.StatusCode)
+	}
+	resp, body := apiRequest(t, ts, "GET", "/api/v1/follows", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("follows: status = %d, want 200", resp.StatusCode)
+	}
+	if items := body["items"].([]any); len(items) != 1 {
+		t.Fatalf("follows: got %d items, want 1", len(items))
+	}
+
+	resp, _ = apiRequest(t, ts, "DELETE", "/api/v1/follows?feed_url=https://example.com/feed", token, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unfollow: status = %d, want 204", resp.StatusCode)
+	}
+	resp, _ = apiRequest(t, ts, "POST", "/api/v1/follows", token, `{"feed_url":"https://example.com/missing"}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("follow missing feed: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	ts, store, token := newTestAPI(t)
+	for range 3 {
+		store.feeds = append(store.feeds, database.Feed{ID: uuid.New(), Name: "feed"})
+	}
+
+	tests := []struct {
+		query     string
+		status    int
+		items     int
+		hasNext   bool
+		nextValue float64
+	}{
+		{"?limit=2", http.StatusOK, 2, true, 2},
+		{"?limit=2&offset=2", http.StatusOK, 1, false, 0},
+		{"?limit=0", http.StatusBadRequest, 0, false, 0},
+		{"?limit=101", http.StatusBadRequest, 0, false, 0},
+		{"?offset=-1", http.StatusBadRequest, 0, false, 0},
+	}
+	for _, tt := range tests {
+		resp, body := apiRequest(t, ts, "GET", "/api/v1/feeds"+tt.query, token, "")
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.query, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if items := body["items"].([]any); len(items) != tt.items {
+			t.Errorf("%s: got %d items, want %d", tt.query, len(items), tt.items)
+		}
+		next, hasNext := body["next_offset"].(float64)
+		if hasNext != tt.hasNext || next != tt.nextValue {
+			t.Errorf("%s: next_offset = %v, want %v", tt.query, body["next_offset"], tt.nextValue)
+		}
+	}
+}
+
+func TestAPIPostActions(t *testing.T) {
+	ts, store, token := newTestAPI(t)
+	post := database.GetPostDetailsRow{ID: uuid.New(), Title: "Hello", FeedID: uuid.New(), FeedName: "Blog"}
+	store.posts[post.ID] = post
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/posts/" + post.ID.String() + "/read", http.StatusNoContent},
+		{"/api/v1/posts/" + post.ID.String() + "/star", http.StatusNoContent},
+		{"/api/v1/posts/" + post.ID.String() + "/bookmark", http.StatusNotFound},
+		{"/api/v1/posts/not-an-id/read", http.StatusBadRequest},
+		{"/api/v1/posts/" + uuid.NewString() + "/read", http.StatusNotFound},
+		{"/api/v1/posts/" + uuid.NewString() + "/star", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, body := apiRequest(t, ts, "POST", tt.path, token, "")
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if msg, _ := body["error"].(string); strings.Contains(msg, "pq:") {
+			t.Errorf("%s: error leaks database details: %q", tt.path, msg)
+		}
+	}
+
+	resp, body := apiRequest(t, ts, "GET", "/api/v1/posts", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("posts: status = %d, want 200", resp.StatusCode)
+	}
+	items := body["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("posts: got %d items, want 1", len(items))
+	}
+	got := items[0].(map[string]any)
+	if got["read"] != true || got["starred"] != true {
+		t.Errorf("posts: read = %v, starred = %v, want both true", got["read"], got["starred"])
+	}
+
+	resp, body = apiRequest(t, ts, "GET", "/api/v1/posts?unread=true", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unread posts: status = %d, want 200", resp.StatusCode)
+	}
+	if items := body["items"].([]any); len(items) != 0 {
+		t.Errorf("unread posts: got %d items, want 0", len(items))
+	}
+}
+
+func TestAPIHidesInternalErrors(t *testing.T) {
+	ts, store, token := newTestAPI(t)
+	store.err = errors.New(`pq: relation "feeds" does not exist`)
+
+	for _, path := range []string{"/api/v1/feeds", "/api/v1/users", "/api/v1/posts"} {
+		resp, body := apiRequest(t, ts, "GET", path, token, "")
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("%s: status = %d, want 500", path, resp.StatusCode)
+		}
+		if body["error"] != "internal server error" {
+			t.Errorf("%s: error = %q, want a generic message", path, body["error"])
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// The Fever API (https://feedafever.com/api) is served at /fever/. Clients
+// POST their api_key and name the data they want in the query string, for
+// example /fever/?api&items&since_id=42.
+const (
+	feverAPIVersion = 3
+	feverItemLimit  = 50
+	// feverGroupID is the single group every followed feed belongs to.
+	feverGroupID = 1
+)
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func (srv *server) handleFever(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]any{
+		"api_version": feverAPIVersion,
+		"auth":        0,
+	}
+
+	ctx := r.Context()
+	apiKey := strings.ToLower(r.FormValue("api_key"))
+	user, err := srv.s.db.GetUserByFeverAPIKey(ctx, sql.NullString{String: apiKey, Valid: apiKey != ""})
+	if err != nil {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	resp["auth"] = 1
+
+	if err := srv.feverResponse(ctx, r, user, resp); err != nil {
+		if errors.Is(err, errFeverBadRequest) {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeAPIErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+var errFeverBadRequest = errors.New("invalid Fever request")
+
+// feverResponse fills resp with the sections requested by r, applying any
+// mark request first so the returned ids reflect it.
+func (srv *server) feverResponse(ctx context.Context, r *http.Request, user database.User, resp map[string]any) error {
+	query := r.URL.Query()
+	db := srv.s.db
+
+	feeds, err := db.ListFeverFeeds(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list feeds: %w", err)
+	}
+	var lastRefreshed int64
+	for _, feed := range feeds {
+		if feed.LastFetchedAt.Valid {
+			lastRefreshed = max(lastRefreshed, feed.LastFetchedAt.Time.Unix())
+		}
+	}
+	resp["last_refreshed_on_time"] = lastRefreshed
+
+	if mark := r.FormValue("mark"); mark != "" {
+		section, err := srv.feverMark(ctx, r, user, mark)
+		if err != nil {
+			return err
+		}
+		query.Set(section, "")
+	}
+
+	if query.Has("groups") || query.Has("feeds") {
+		feedIDs := make([]string, 0, len(feeds))
+		for _, feed := range feeds {
+			feedIDs = append(feedIDs, strconv.FormatInt(feed.FeverID, 10))
+		}
+		resp["feeds_groups"] = []feverFeedsGroup{{GroupID: feverGroupID, FeedIDs: strings.Join(feedIDs, ",")}}
+	}
+	if query.Has("groups") {
+		resp["groups"] = []feverGroup{{ID: feverGroupID, Title: "All"}}
+	}
+	if query.Has("feeds") {
+		items := make([]feverFeed, 0, len(feeds))
+		for _, feed := range feeds {
+			item := feverFeed{
+				ID:      feed.FeverID,
+				Title:   feed.Name,
+				URL:     feed.Url,
+				SiteURL: feed.Url,
+			}
+			if feed.LastFetchedAt.Valid {
+				item.LastUpdatedOnTime = feed.LastFetchedAt.Time.Unix()
+			}
+			items = append(items, item)
+		}
+		resp["feeds"] = items
+	}
+	if query.Has("favicons") {
+		resp["favicons"] = []any{}
+	}
+	if query.Has("links") {
+		resp["links"] = []any{}
+	}
+
+	if query.Has("items") {
+		items, err := srv.feverItems(ctx, query, user)
+		if err != nil {
+			return err
+		}
+		total, err := db.CountFeverItems(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count items: %w", err)
+		}
+		resp["items"] = items
+		resp["total_items"] = total
+	}
+	if query.Has("unread_item_ids") {
+		ids, err := db.ListUnreadFeverItemIDs(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list unread items: %w", err)
+		}
+		resp["unread_item_ids"] = joinIDs(ids)
+	}
+	if query.Has("saved_item_ids") {
+		ids, err := db.ListSavedFeverItemIDs(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list saved items: %w", err)
+		}
+		resp["saved_item_ids"] = joinIDs(ids)
+	}
+	return nil
+}
+
+func (srv *server) feverItems(ctx context.Context, query url.Values, user database.User) ([]feverItem, error) {
+	params := database.ListFeverItemsParams{
+		UserID:  user.ID,
+		WithIds: []int64{},
+		Limit:   feverItemLimit,
+	}
+	var err error
+	if v := query.Get("since_id"); v != "" {
+		if params.SinceID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: since_id must be an integer", errFeverBadRequest)
+		}
+	}
+	if v := query.Get("max_id"); v != "" {
+		if params.MaxID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: max_id must be an integer", errFeverBadRequest)
+		}
+	}
+	if v := query.Get("with_ids"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: with_ids must be a list of integers", errFeverBadRequest)
+			}
+			params.WithIds = append(params.WithIds, id)
+		}
+	}
+
+	rows, err := srv.s.db.ListFeverItems(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+
+	items := make([]feverItem, 0, len(rows))
+	for _, row := range rows {
+		item := feverItem{
+			ID:            row.FeverID,
+			FeedID:        row.FeedFeverID,
+			Title:         row.Title,
+			Author:        row.Author.String,
+			HTML:          row.Description.String,
+			URL:           row.Url,
+			CreatedOnTime: row.CreatedAt.Unix(),
+		}
+		if row.Content.Valid {
+			item.HTML = row.Content.String
+		}
+		if row.PublishedAt.Valid {
+			item.CreatedOnTime = row.PublishedAt.Time.Unix()
+		}
+		if row.Read {
+			item.IsRead = 1
+		}
+		if row.Starred {
+			item.IsSaved = 1
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// feverMark applies a mark request and returns the response section that
+// reflects it.
+func (srv *server) feverMark(ctx context.Context, r *http.Request, user database.User, mark string) (string, error) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: id must be an integer", errFeverBadRequest)
+	}
+	as := r.FormValue("as")
+	db := srv.s.db
+
+	switch mark {
+	case "item":
+		postID, err := db.GetPostIDByFeverID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: unknown item %d", errFeverBadRequest, id)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get item %d: %w", id, err)
+		}
+		switch as {
+		case "read", "unread":
+			return "unread_item_ids", setPostRead(ctx, db, user, postID, as == "read")
+		case "saved", "unsaved":
+			return "saved_item_ids", setPostStarred(ctx, db, user, postID, as == "saved")
+		}
+	case "feed", "group":
+		if as != "read" {
+			break
+		}
+		before, err := strconv.ParseInt(r.FormValue("before"), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: before must be a Unix timestamp", errFeverBadRequest)
+		}
+		// Groups 0 (Kindling) and feverGroupID both contain every feed.
+		feedID := id
+		if mark == "feed" && id <= 0 {
+			return "", fmt.Errorf("%w: unknown feed %d", errFeverBadRequest, id)
+		}
+		if mark == "group" {
+			if id != 0 && id != feverGroupID {
+				return "unread_item_ids", nil
+			}
+			feedID = 0
+		}
+		err = db.MarkFeverPostsRead(ctx, database.MarkFeverPostsReadParams{
+			UserID:      user.ID,
+			FeedFeverID: feedID,
+			Before:      time.Unix(before, 0),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to mark %s as read: %w", mark, err)
+		}
+		return "unread_item_ids", nil
+	}
+	return "", fmt.Errorf("%w: cannot mark %s as %q", errFeverBadRequest, mark, as)
+}
+
+func joinIDs(ids []int64) string {
+	fields := make([]string, 0, len(ids))
+	for _, id := range ids {
+		fields = append(fields, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(fields, ",")
+}
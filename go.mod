@@ -4,4 +4,14 @@ go 1.23.6
 
 require github.com/google/uuid v1.6.0
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
+
+require (
+	github.com/andybalholm/brotli v1.2.0
+	golang.org/x/crypto v0.39.0
+	golang.org/x/net v0.41.0
+	golang.org/x/term v0.32.0
+	golang.org/x/text v0.26.0
+)
+
+require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+const (
+	roleAdmin = "admin"
+	roleUser  = "user"
+)
+
+func handlerRole(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("role requires a username and a role (%s or %s)", roleAdmin, roleUser)
+	}
+	name, role := cmd.args[0], cmd.args[1]
+	if role != roleAdmin && role != roleUser {
+		return fmt.Errorf("unknown role %q; use %s or %s", role, roleAdmin, roleUser)
+	}
+
+	ctx := context.Background()
+	if role == roleUser {
+		if err := checkNotLastAdmin(ctx, s.db, name); err != nil {
+			return err
+		}
+	}
+	n, err := s.db.SetUserRole(ctx, database.SetUserRoleParams{Name: name, Role: role})
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("user %s not found", name)
+	}
+
+	fmt.Printf("Set role of %s to %s\n", name, role)
+	return nil
+}
+
+// checkNotLastAdmin refuses to remove the last administrator, which would
+// leave nobody able to run admin commands.
+func checkNotLastAdmin(ctx context.Context, db *database.Queries, name string) error {
+	user, err := db.GetUser(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", name, err)
+	}
+	if user.Role != roleAdmin {
+		return nil
+	}
+	admins, err := db.CountAdmins(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins <= 1 {
+		return fmt.Errorf("%s is the last admin; make another user an admin first", name)
+	}
+	return nil
+}
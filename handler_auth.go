@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+	"golang.org/x/term"
+)
+
+// readPassword prompts for a password without echoing it. When stdin is not
+// a terminal the password is read as a line, so scripts can pipe it in.
+func readPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(password), nil
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptNewPassword asks for a new password twice and returns its hash.
+func promptNewPassword() (string, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := readPassword("Confirm password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirmation {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return auth.HashPassword(password)
+}
+
+// authenticate checks password against user's stored hash.
+func authenticate(user database.User, password string) error {
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("user %s has no password; ask an admin for a reset token", user.Name)
+	}
+	return auth.CheckPassword(user.PasswordHash.String, password)
+}
+
+// createSession starts a session for user and returns its token.
+func createSession(ctx context.Context, db apiStore, user database.User) (string, database.Session, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return "", database.Session{}, err
+	}
+	session, err := db.CreateSession(ctx, database.CreateSessionParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(auth.SessionDuration),
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+	})
+	if err != nil {
+		return "", database.Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, session, nil
+}
+
+// sessionUser returns the user a session token belongs to.
+func sessionUser(ctx context.Context, db apiStore, token string) (database.User, error) {
+	if token == "" {
+		return database.User{}, fmt.Errorf("not logged in")
+	}
+	user, err := db.GetUserBySessionToken(ctx, auth.HashToken(token))
+	if err != nil {
+		return database.User{}, fmt.Errorf("session expired or invalid: %w", err)
+	}
+	return user, nil
+}
+
+// loginLocal replaces the session stored in the config file with a new
+// session for user.
+func loginLocal(ctx context.Context, s *state, user database.User) error {
+	if s.cfg.SessionToken != "" {
+		if err := s.db.DeleteSession(ctx, auth.HashToken(s.cfg.SessionToken)); err != nil {
+			return fmt.Errorf("failed to end previous session: %w", err)
+		}
+	}
+	token, _, err := createSession(ctx, s.db, user)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.SetSession(user.Name, token); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("logout does not take any arguments")
+	}
+	if s.cfg.SessionToken == "" {
+		return fmt.Errorf("not logged in")
+	}
+
+	ctx := context.Background()
+	if err := s.db.DeleteSession(ctx, auth.HashToken(s.cfg.SessionToken)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := s.db.DeleteExpiredSessions(ctx); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	name := s.cfg.CurrentUserName
+	if err := s.cfg.ClearSession(); err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+
+	fmt.Printf("Logged out %s\n", name)
+	return nil
+}
+
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("passwd does not take any arguments")
+	}
+
+	current, err := readPassword("Current password: ")
+	if err != nil {
+		return err
+	}
+	if err := authenticate(user, current); err != nil {
+		return err
+	}
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := setPassword(ctx, s.db, user, hash); err != nil {
+		return err
+	}
+	// Every session ended with the old password, including this one.
+	token, _, err := createSession(ctx, s.db, user)
+	if err != nil {
+		return err
+	}
+	if err := s.cfg.SetSession(user.Name, token); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	fmt.Printf("Changed password for %s; other sessions were logged out\n", user.Name)
+	return nil
+}
+
+// resetPassword asks for the reset token issued for user and, if it is
+// valid, for a new password. The token can only be used once, and the
+// user's existing sessions are ended.
+func resetPassword(ctx context.Context, db *database.Queries, user database.User) error {
+	token, err := readPassword("Reset token: ")
+	if err != nil {
+		return err
+	}
+	reset, err := db.GetPasswordReset(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no valid reset token for %s; ask an admin for one", user.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reset token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(token)), []byte(reset.TokenHash)) != 1 {
+		return fmt.Errorf("invalid reset token")
+	}
+
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setPassword(ctx, db, user, hash); err != nil {
+		return err
+	}
+	if err := db.DeletePasswordReset(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete reset token: %w", err)
+	}
+	return nil
+}
+
+// setPassword replaces user's password and ends their sessions.
+func setPassword(ctx context.Context, db *database.Queries, user database.User, hash string) error {
+	err := db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: sql.NullString{String: hash, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	if err := db.DeleteUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to end sessions: %w", err)
+	}
+	return nil
+}
+
+// handlerBootstrap sets the password of an admin while no user has one yet,
+// which is the case right after upgrading from a version without passwords.
+// From then on passwords are set with reset tokens issued by an admin.
+func handlerBootstrap(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("bootstrap requires the name of an admin")
+	}
+
+	ctx := context.Background()
+	n, err := s.db.CountUsersWithPassword(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count users: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("bootstrap can only be used while no user has a password; ask an admin for a reset token")
+	}
+	user, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Role != roleAdmin {
+		return fmt.Errorf("%s is not an admin", user.Name)
+	}
+
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setPassword(ctx, s.db, user, hash); err != nil {
+		return err
+	}
+	if err := loginLocal(ctx, s, user); err != nil {
+		return err
+	}
+	fmt.Printf("Set the password of %s and logged in\n", user.Name)
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/config"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/digest"
+)
+
+// digestMaxPosts caps the number of posts in one digest.
+const digestMaxPosts = 500
+
+// A scheduled digest that fails to send is retried after digestRetryDelay,
+// doubling with each further failure up to digestMaxRetryDelay.
+const (
+	digestRetryDelay    = time.Hour
+	digestMaxRetryDelay = 24 * time.Hour
+)
+
+// digestPeriods are the schedules digests can be sent on by agg.
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	since := fs.Duration("since", 24*time.Hour, "include posts fetched within this long")
+	to := fs.String("to", "", "address to send the digest to")
+	schedule := fs.String("schedule", "", "have agg send a digest to --to: daily, weekly or off")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("digest does not take any arguments")
+	}
+
+	ctx := context.Background()
+	if *schedule != "" {
+		return setDigestSchedule(ctx, s.db, user, *schedule, *to)
+	}
+	if *to == "" {
+		return fmt.Errorf("digest requires --to")
+	}
+
+	mailer, from, err := mailerFromConfig(s.cfg.SMTP)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	d, err := buildDigest(ctx, s.db, user.ID, now.Add(-*since))
+	if err != nil {
+		return err
+	}
+	if d.Len() == 0 {
+		fmt.Printf("No new posts since %s\n", d.Since.Format(time.RFC1123))
+		return nil
+	}
+	if err := sendDigest(mailer, from, *to, d, now); err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent a digest of %d posts to %s\n", d.Len(), *to)
+	return nil
+}
+
+func setDigestSchedule(ctx context.Context, db *database.Queries, user database.User, frequency, to string) error {
+	if frequency == "off" {
+		n, err := db.DeleteDigestSchedule(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to remove digest schedule: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("no digest is scheduled for %s", user.Name)
+		}
+		fmt.Println("Stopped scheduled digests")
+		return nil
+	}
+
+	if _, ok := digestPeriods[frequency]; !ok {
+		return fmt.Errorf("unknown schedule %q; use daily, weekly or off", frequency)
+	}
+	if to == "" {
+		return fmt.Errorf("digest --schedule requires --to")
+	}
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("invalid address %q: %w", to, err)
+	}
+
+	now := time.Now()
+	_, err := db.UpsertDigestSchedule(ctx, database.UpsertDigestScheduleParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		Email:     to,
+		Frequency: frequency,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save digest schedule: %w", err)
+	}
+
+	fmt.Printf("agg will send a %s digest to %s\n", frequency, to)
+	return nil
+}
+
+// sendDueDigests sends the scheduled digests whose period has passed since
+// they were last sent. A digest without posts is skipped but counts as sent.
+// A digest that fails to send is put off, see digestRetryDelay.
+func sendDueDigests(ctx context.Context, s *state) error {
+	mailer, from, err := mailerFromConfig(s.cfg.SMTP)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	schedules, err := s.db.ListDueDigestSchedules(ctx, database.ListDueDigestSchedulesParams{
+		DailyBefore:  sql.NullTime{Time: now.Add(-digestPeriods["daily"]), Valid: true},
+		WeeklyBefore: sql.NullTime{Time: now.Add(-digestPeriods["weekly"]), Valid: true},
+		Now:          sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list due digests: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		since := now.Add(-digestPeriods[schedule.Frequency])
+		if schedule.LastSentAt.Valid {
+			since = schedule.LastSentAt.Time
+		}
+		d, err := buildDigest(ctx, s.db, schedule.UserID, since)
+		if err != nil {
+			return err
+		}
+		if d.Len() > 0 {
+			if err := sendDigest(mailer, from, schedule.Email, d, now); err != nil {
+				retryAt := now.Add(retryAfter(schedule.Failures, digestRetryDelay, digestMaxRetryDelay))
+				fmt.Printf("failed to send digest to %s, retrying at %s: %s\n", schedule.UserName, retryAt.Format(time.RFC1123), err)
+				err = s.db.SetDigestFailed(ctx, database.SetDigestFailedParams{
+					ID:      schedule.ID,
+					RetryAt: sql.NullTime{Time: retryAt, Valid: true},
+				})
+				if err != nil {
+					return fmt.Errorf("failed to record digest failure: %w", err)
+				}
+				continue
+			}
+			fmt.Printf("Sent a %s digest of %d posts to %s\n", schedule.Frequency, d.Len(), schedule.UserName)
+		}
+		err = s.db.SetDigestSent(ctx, database.SetDigestSentParams{
+			ID:         schedule.ID,
+			LastSentAt: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record digest: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildDigest collects the unread posts fetched since since from the feeds
+// userID follows, grouped by feed. Muted feeds and filtered posts are left
+// out, as in browse.
+func buildDigest(ctx context.Context, db *database.Queries, userID uuid.UUID, since time.Time) (digest.Digest, error) {
+	posts, err := db.GetDigestPosts(ctx, database.GetDigestPostsParams{
+		UserID: userID,
+		Since:  since,
+		Limit:  digestMaxPosts,
+	})
+	if err != nil {
+		return digest.Digest{}, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	d := digest.Digest{Since: since}
+	var feedID uuid.UUID
+	for _, post := range posts {
+		if len(d.Feeds) == 0 || post.FeedID != feedID {
+			d.Feeds = append(d.Feeds, digest.Feed{Name: post.FeedName})
+			feedID = post.FeedID
+		}
+		feed := &d.Feeds[len(d.Feeds)-1]
+		feed.Posts = append(feed.Posts, digest.Post{
+			Title:   post.Title,
+			URL:     post.Url,
+			Summary: post.Summary.String,
+		})
+	}
+	return d, nil
+}
+
+func sendDigest(mailer digest.SMTP, from, to string, d digest.Digest, now time.Time) error {
+	msg, err := d.Message(from, to, now)
+	if err != nil {
+		return err
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	toAddr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	if err := mailer.Send(fromAddr.Address, []string{toAddr.Address}, msg); err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+	return nil
+}
+
+func mailerFromConfig(cfg config.SMTPConfig) (digest.SMTP, string, error) {
+	if cfg.Host == "" {
+		return digest.SMTP{}, "", fmt.Errorf("smtp.host is not set in ~/.gatorconfig.json")
+	}
+	if cfg.From == "" {
+		return digest.SMTP{}, "", fmt.Errorf("smtp.from is not set in ~/.gatorconfig.json")
+	}
+	mailer := digest.SMTP{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		TLS:      cfg.TLS,
+	}
+	return mailer, cfg.From, nil
+}
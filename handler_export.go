@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/rss"
+)
+
+const defaultExportLimit = 50
+
+// timelineOptions selects the posts republished by export-feed and the
+// web server's feed endpoints.
+type timelineOptions struct {
+	feedID  uuid.NullUUID
+	tag     string
+	limit   int
+	link    string
+	selfURL string
+}
+
+func handlerExportFeed(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	userName := fs.String("user", user.Name, "user whose timeline is exported; admins only")
+	format := fs.String("format", "rss", "output format: rss or atom")
+	feedURL := fs.String("feed", "", "only export posts from this feed")
+	tag := fs.String("tag", "", "only export posts from feeds with this tag")
+	limit := fs.Int("limit", defaultExportLimit, "maximum number of posts")
+	link := fs.String("link", "", "link to publish as the feed's home page")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("export-feed: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("export-feed does not take any arguments")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("export-feed: --limit must be positive")
+	}
+
+	ctx := context.Background()
+	user, err = exportUser(ctx, s.db, cmd, user, *userName)
+	if err != nil {
+		return err
+	}
+
+	opts := timelineOptions{tag: *tag, limit: *limit, link: *link}
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByURL(ctx, *feedURL)
+		if err != nil {
+			return fmt.Errorf("failed to get feed: %w", err)
+		}
+		opts.feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	timeline, err := userTimeline(ctx, s.db, user, opts)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "rss":
+		return rss.WriteRSS(os.Stdout, timeline)
+	case "atom":
+		return rss.WriteAtom(os.Stdout, timeline)
+	default:
+		return fmt.Errorf("export-feed: unknown format %q", *format)
+	}
+}
+
+func handlerExportOPML(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	userName := fs.String("user", user.Name, "user whose follows are exported; admins only")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("export-opml: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("export-opml does not take any arguments")
+	}
+
+	ctx := context.Background()
+	user, err = exportUser(ctx, s.db, cmd, user, *userName)
+	if err != nil {
+		return err
+	}
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get feed follows: %w", err)
+	}
+
+	subs := make([]rss.Subscription, 0, len(follows))
+	for _, follow := range follows {
+		subs = append(subs, rss.Subscription{
+			Title: follow.FeedName,
+			URL:   follow.FeedUrl,
+			Tags:  follow.Tags,
+		})
+	}
+	return rss.WriteOPML(os.Stdout, fmt.Sprintf("Feeds followed by %s", user.Name), time.Now(), subs)
+}
+
+// exportUser returns the user named by the --user flag of cmd, which only
+// admins may set to anyone but themselves.
+func exportUser(ctx context.Context, db *database.Queries, cmd command, current database.User, name string) (database.User, error) {
+	if name == current.Name {
+		return current, nil
+	}
+	if current.Role != roleAdmin {
+		return database.User{}, fmt.Errorf("%s --user requires an admin user", cmd.command)
+	}
+	user, err := db.GetUser(ctx, name)
+	if err != nil {
+		return database.User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// handlerFeedToken issues a new secret for reading the current user's
+// timeline feeds from gator serve, replacing any earlier one.
+func handlerFeedToken(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	revoke := fs.Bool("revoke", false, "remove the token without issuing a new one")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("feed-token: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("feed-token does not take any arguments")
+	}
+
+	ctx := context.Background()
+	if *revoke {
+		err := s.db.SetUserFeedToken(ctx, database.SetUserFeedTokenParams{ID: user.ID})
+		if err != nil {
+			return fmt.Errorf("failed to remove feed token: %w", err)
+		}
+		fmt.Println("Removed your feed token")
+		return nil
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	err = s.db.SetUserFeedToken(ctx, database.SetUserFeedTokenParams{
+		ID:            user.ID,
+		FeedTokenHash: sql.NullString{String: auth.HashToken(token), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed token: %w", err)
+	}
+
+	fmt.Printf("Subscribe to /users/%s/rss?token=%s on your gator server\n", url.PathEscape(user.Name), token)
+	fmt.Println("Any earlier feed token no longer works.")
+	return nil
+}
+
+// userTimeline collects the newest posts from the feeds user follows into a
+// feed that can be republished.
+func userTimeline(ctx context.Context, db *database.Queries, user database.User, opts timelineOptions) (rss.Timeline, error) {
+	posts, err := db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID:       user.ID,
+		FeedID:       opts.feedID,
+		Tag:          sql.NullString{String: opts.tag, Valid: opts.tag != ""},
+		ApplyFilters: true,
+		Limit:        int32(opts.limit),
+	})
+	if err != nil {
+		return rss.Timeline{}, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	timeline := rss.Timeline{
+		ID:          "urn:uuid:" + user.ID.String(),
+		Title:       fmt.Sprintf("%s's gator timeline", user.Name),
+		Description: fmt.Sprintf("Posts from the feeds %s follows", user.Name),
+		Link:        opts.link,
+		SelfURL:     opts.selfURL,
+		Updated:     user.UpdatedAt,
+	}
+	if opts.tag != "" {
+		timeline.Title = fmt.Sprintf("%s's gator timeline: %s", user.Name, opts.tag)
+	}
+	if opts.feedID.Valid && len(posts) > 0 {
+		timeline.Title = fmt.Sprintf("%s (via %s)", posts[0].FeedName, user.Name)
+	}
+
+	for _, post := range posts {
+		item := rss.TimelineItem{
+			ID:         "urn:uuid:" + post.ID.String(),
+			Title:      post.Title,
+			Link:       post.Url,
+			Summary:    post.Description.String,
+			Content:    post.Content.String,
+			Author:     post.Author.String,
+			Categories: post.Categories,
+			Published:  post.PublishedAt.Time,
+			Updated:    post.UpdatedAt,
+			Source:     rss.TimelineSource{Title: post.FeedName, URL: post.FeedUrl},
+		}
+		if post.UpdatedAt.After(timeline.Updated) {
+			timeline.Updated = post.UpdatedAt
+		}
+		timeline.Items = append(timeline.Items, item)
+	}
+	return timeline, nil
+}
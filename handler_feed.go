@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+func handlerFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("feed requires a subcommand: rename, set-url or delete")
+	}
+
+	sub := command{command: "feed " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "rename":
+		return handlerFeedRename(s, sub, user)
+	case "set-url":
+		return handlerFeedSetURL(s, sub, user)
+	case "delete":
+		return handlerFeedDelete(s, sub, user)
+	default:
+		return fmt.Errorf("unknown feed subcommand: %s", cmd.args[0])
+	}
+}
+
+// getManagedFeed returns the feed at feedURL if user may edit it: admins may
+// edit any feed, other users only the feeds they added.
+func getManagedFeed(ctx context.Context, db *database.Queries, user database.User, feedURL string) (database.Feed, error) {
+	feed, err := db.GetFeedByURL(ctx, feedURL)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to get feed: %w", err)
+	}
+	if user.Role != roleAdmin && (!feed.UserID.Valid || feed.UserID.UUID != user.ID) {
+		return database.Feed{}, fmt.Errorf("only the user who added %s or an admin can change it", feed.Name)
+	}
+	return feed, nil
+}
+
+func handlerFeedRename(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("%s requires a feed URL and a new name", cmd.command)
+	}
+	feedURL, name := cmd.args[0], cmd.args[1]
+
+	ctx := context.Background()
+	feed, err := getManagedFeed(ctx, s.db, user, feedURL)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.RenameFeed(ctx, database.RenameFeedParams{Url: feedURL, Name: name}); err != nil {
+		return fmt.Errorf("failed to rename feed: %w", err)
+	}
+
+	fmt.Printf("Renamed feed %s to %s\n", feed.Name, name)
+	return nil
+}
+
+func handlerFeedSetURL(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("%s requires the current and the new feed URL", cmd.command)
+	}
+	oldURL, newURL := cmd.args[0], cmd.args[1]
+
+	ctx := context.Background()
+	feed, err := getManagedFeed(ctx, s.db, user, oldURL)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.SetFeedURL(ctx, database.SetFeedURLParams{NewUrl: newURL, OldUrl: oldURL})
+	if database.IsUniqueViolation(err) {
+		return fmt.Errorf("another feed already uses %s", newURL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set feed URL: %w", err)
+	}
+
+	fmt.Printf("Feed %s now fetches %s\n", feed.Name, newURL)
+	return nil
+}
+
+func handlerFeedDelete(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.command, err)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%s requires a feed URL", cmd.command)
+	}
+	if user.Role != roleAdmin {
+		return fmt.Errorf("%s requires an admin user", cmd.command)
+	}
+
+	ctx := context.Background()
+	feed, err := s.db.GetFeedByURL(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
+	followers, err := s.db.CountFeedFollowers(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count followers: %w", err)
+	}
+
+	if !*yes {
+		fmt.Printf("Feed %s has %d followers. Deleting it removes the feed and all of its posts for every one of them.\n", feed.Name, followers)
+		if err := confirm("delete"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.DeleteFeedByURL(ctx, feed.Url); err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+
+	fmt.Printf("Deleted feed %s and its posts\n", feed.Name)
+	return nil
+}
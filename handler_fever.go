@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// feverAPIKey returns the key Fever clients send for a user: the MD5 hex
+// digest of "<email>:<password>", where gator uses the user name as the email.
+func feverAPIKey(userName, password string) string {
+	sum := md5.Sum([]byte(userName + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func handlerFeverPassword(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("fever-password takes no arguments; it prompts for the password")
+	}
+	password, err := readPassword("Fever password: ")
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("the Fever password must not be empty")
+	}
+
+	err = s.db.SetUserFeverAPIKey(context.Background(), database.SetUserFeverAPIKeyParams{
+		ID:          user.ID,
+		FeverApiKey: sql.NullString{String: feverAPIKey(user.Name, password), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set Fever API key: %w", err)
+	}
+
+	fmt.Printf("Set Fever API password for %s\n", user.Name)
+	fmt.Printf("Log in to the Fever API at /fever/ with %q as the email\n", user.Name)
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// filterFields are the parts of a post a filter can match. text matches the
+// title or the description.
+var filterFields = []string{"text", "title", "description", "author", "category"}
+
+func handlerFilter(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("filter requires a subcommand: add, list or rm")
+	}
+
+	sub := command{command: "filter " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "add":
+		return handlerFilterAdd(s, sub, user)
+	case "list":
+		return handlerFilterList(s, sub, user)
+	case "rm":
+		return handlerFilterRemove(s, sub, user)
+	default:
+		return fmt.Errorf("unknown filter subcommand: %s", cmd.args[0])
+	}
+}
+
+func handlerFilterAdd(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	field := fs.String("field", "text", "the part of a post to match")
+	feedURL := fs.String("feed", "", "only filter posts of the feed at this URL")
+	regex := fs.Bool("regex", false, "match a case-insensitive regular expression")
+	markRead := fs.Bool("mark-read", false, "mark matching posts as read when they are fetched")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.command, err)
+	}
+	if len(args) != 1 || args[0] == "" {
+		return fmt.Errorf("%s requires a pattern", cmd.command)
+	}
+	pattern := args[0]
+	if !slices.Contains(filterFields, *field) {
+		return fmt.Errorf("unknown field %q; use one of %v", *field, filterFields)
+	}
+
+	ctx := context.Background()
+	if *regex {
+		// Filters are matched by the database, so the pattern must be a
+		// valid PostgreSQL regular expression.
+		if _, err := s.db.CheckFilterRegex(ctx, pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+	var feedID uuid.NullUUID
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByURL(ctx, *feedURL)
+		if err != nil {
+			return fmt.Errorf("failed to get feed: %w", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	filter, err := s.db.CreateFilter(ctx, database.CreateFilterParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Field:     *field,
+		Pattern:   pattern,
+		Regex:     *regex,
+		MarkRead:  *markRead,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create filter: %w", err)
+	}
+
+	fmt.Printf("Added filter %s\n", filter.ID)
+	return nil
+}
+
+func handlerFilterList(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("%s does not take any arguments", cmd.command)
+	}
+
+	filters, err := s.db.ListFiltersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list filters: %w", err)
+	}
+	if len(filters) == 0 {
+		fmt.Println("No filters")
+		return nil
+	}
+
+	for _, filter := range filters {
+		match := "contains"
+		if filter.Regex {
+			match = "matches"
+		}
+		fmt.Printf("* %s %s %q\n", filter.Field, match, filter.Pattern)
+		fmt.Printf("  id: %s\n", filter.ID)
+		if filter.FeedName.Valid {
+			fmt.Printf("  feed: %s\n", filter.FeedName.String)
+		} else {
+			fmt.Println("  feed: all")
+		}
+		if filter.MarkRead {
+			fmt.Println("  marks new posts as read")
+		}
+	}
+	return nil
+}
+
+func handlerFilterRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("%s requires a filter ID", cmd.command)
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse filter ID: %w", err)
+	}
+
+	n, err := s.db.DeleteFilter(context.Background(), database.DeleteFilterParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("failed to remove filter: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("filter %s not found", id)
+	}
+
+	fmt.Printf("Removed filter %s\n", id)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// handlerFollowSet changes how a followed feed is shown to the current user
+// without affecting other followers. Only the flags given are changed.
+func handlerFollowSet(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	name := fs.String("name", "", "name to show for the feed; empty uses the feed's own name")
+	priority := fs.Int("priority", 0, "feeds with a higher priority are listed first in following, browse and digests")
+	muted := fs.Bool("muted", false, "hide the feed's posts from browse")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.command, err)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%s requires a feed URL", cmd.command)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if len(set) == 0 {
+		return fmt.Errorf("%s requires at least one of --name, --priority or --muted", cmd.command)
+	}
+
+	ctx := context.Background()
+	feed, err := s.db.GetFeedByURL(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
+	follow, err := s.db.GetFeedFollow(ctx, database.GetFeedFollowParams{FeedID: feed.ID, UserID: user.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("you do not follow %s", feed.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get feed follow: %w", err)
+	}
+
+	params := database.UpdateFeedFollowSettingsParams{
+		FeedID:      feed.ID,
+		UserID:      user.ID,
+		DisplayName: follow.DisplayName,
+		Priority:    follow.Priority,
+		Muted:       follow.Muted,
+	}
+	if set["name"] {
+		params.DisplayName = sql.NullString{String: *name, Valid: *name != ""}
+	}
+	if set["priority"] {
+		params.Priority = int32(*priority)
+	}
+	if set["muted"] {
+		params.Muted = *muted
+	}
+	if err := s.db.UpdateFeedFollowSettings(ctx, params); err != nil {
+		return fmt.Errorf("failed to update feed follow: %w", err)
+	}
+
+	displayName := feed.Name
+	if params.DisplayName.Valid {
+		displayName = params.DisplayName.String
+	}
+	fmt.Printf("%s: name %q, priority %d, muted %t\n", feed.Url, displayName, params.Priority, params.Muted)
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/podcast"
+	"github.com/jasonwashburn/gator/internal/rss"
+)
+
+const (
+	// autoDownloadBatchSize caps how many episodes agg downloads between two
+	// feed fetches.
+	autoDownloadBatchSize = 5
+	// downloadTimeout bounds a single episode download, which can take much
+	// longer than fetching a feed.
+	downloadTimeout = time.Hour
+	// An episode that fails to download is retried after downloadRetryDelay,
+	// doubling with each further failure up to downloadMaxRetryDelay, and
+	// given up on after autoDownloadMaxFailures attempts.
+	downloadRetryDelay      = time.Hour
+	downloadMaxRetryDelay   = 24 * time.Hour
+	autoDownloadMaxFailures = 5
+)
+
+func handlerPodcast(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("podcast requires a subcommand: download or auto")
+	}
+
+	sub := command{command: "podcast " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "download":
+		return handlerPodcastDownload(s, sub)
+	case "auto":
+		return handlerPodcastAuto(s, sub, user)
+	default:
+		return fmt.Errorf("unknown podcast subcommand: %s", cmd.args[0])
+	}
+}
+
+func handlerPodcastDownload(s *state, cmd command) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	post, err := s.db.GetPostDetails(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	enclosures, err := s.db.ListPostEnclosures(context.Background(), post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures: %w", err)
+	}
+	if len(enclosures) == 0 {
+		return fmt.Errorf("post has no enclosures to download")
+	}
+	fetcher, err := fetcherFromConfig(s.cfg.Fetch)
+	if err != nil {
+		return err
+	}
+	client := downloadClient(fetcher)
+
+	for _, enclosure := range enclosures {
+		err := downloadEnclosure(context.Background(), s, client, enclosure.ID, enclosure.Url, post.FeedName, post.Title)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handlerPodcastAuto(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 || (cmd.args[1] != "on" && cmd.args[1] != "off") {
+		return fmt.Errorf("podcast auto requires a feed URL and on or off")
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	// Only episodes published from now on are downloaded automatically, so
+	// turning the rule on does not fetch a podcast's whole back catalogue.
+	var since sql.NullTime
+	if cmd.args[1] == "on" {
+		since = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	updated, err := s.db.SetFeedFollowAutoDownload(context.Background(), database.SetFeedFollowAutoDownloadParams{
+		FeedID:            feed.ID,
+		UserID:            user.ID,
+		AutoDownloadSince: since,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update feed follow: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("you do not follow %s", feed.Url)
+	}
+
+	fmt.Printf("Auto-download for %s: %s\n", feed.Name, cmd.args[1])
+	return nil
+}
+
+// downloadPendingEpisodes downloads new episodes of feeds that have an
+// auto-download rule. An episode that fails to download is put off, see
+// downloadRetryDelay, so that it does not hold up the others.
+func downloadPendingEpisodes(ctx context.Context, s *state, fetcher *rss.Fetcher) error {
+	now := time.Now()
+	pending, err := s.db.ListPendingAutoDownloads(ctx, database.ListPendingAutoDownloadsParams{
+		Limit:       autoDownloadBatchSize,
+		MaxFailures: autoDownloadMaxFailures,
+		Now:         sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pending downloads: %w", err)
+	}
+
+	client := downloadClient(fetcher)
+	for _, enclosure := range pending {
+		err := downloadEnclosure(ctx, s, client, enclosure.ID, enclosure.Url, enclosure.FeedName, enclosure.PostTitle)
+		if err == nil {
+			continue
+		}
+		retryAt := now.Add(retryAfter(enclosure.DownloadFailures, downloadRetryDelay, downloadMaxRetryDelay))
+		if enclosure.DownloadFailures+1 >= autoDownloadMaxFailures {
+			fmt.Printf("%s; giving up\n", err)
+		} else {
+			fmt.Printf("%s; retrying at %s\n", err, retryAt.Format(time.RFC1123))
+		}
+		err = s.db.MarkEnclosureDownloadFailed(ctx, database.MarkEnclosureDownloadFailedParams{
+			ID:              enclosure.ID,
+			DownloadRetryAt: sql.NullTime{Time: retryAt, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record download failure: %w", err)
+		}
+	}
+	return nil
+}
+
+// downloadClient returns a client that downloads through the fetch
+// settings, such as the proxy and CA bundle, with a deadline fit for large
+// files.
+func downloadClient(fetcher *rss.Fetcher) *http.Client {
+	client := fetcher.Client()
+	client.Timeout = downloadTimeout
+	return client
+}
+
+func downloadEnclosure(ctx context.Context, s *state, client *http.Client, enclosureID uuid.UUID, enclosureURL, feedName, postTitle string) error {
+	dir, err := s.cfg.PodcastDirectory()
+	if err != nil {
+		return fmt.Errorf("failed to get podcast directory: %w", err)
+	}
+	// Episodes of a feed can share a title, so the enclosure ID keeps their
+	// files apart.
+	dest := filepath.Join(dir, podcast.SafeName(feedName), podcast.FileName(postTitle, enclosureID.String(), enclosureURL))
+
+	fmt.Printf("Downloading %s to %s\n", enclosureURL, dest)
+	size, err := podcast.Download(ctx, client, enclosureURL, dest)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", enclosureURL, err)
+	}
+
+	err = s.db.MarkEnclosureDownloaded(ctx, database.MarkEnclosureDownloadedParams{
+		ID:        enclosureID,
+		LocalPath: sql.NullString{String: dest, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark enclosure as downloaded: %w", err)
+	}
+
+	fmt.Printf("Downloaded %d bytes to %s\n", size, dest)
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jasonwashburn/gator/internal/rss"
+)
+
+func handlerPost(s *state, cmd command) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+
+	post, err := s.db.GetPostDetails(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	enclosures, err := s.db.ListPostEnclosures(context.Background(), post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures: %w", err)
+	}
+	edits, err := s.db.ListPostEdits(context.Background(), post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get post edits: %w", err)
+	}
+
+	fmt.Println(post.Title)
+	fmt.Printf("URL:        %s\n", post.Url)
+	fmt.Printf("Feed:       %s\n", post.FeedName)
+	if post.PublishedAt.Valid {
+		fmt.Printf("Published:  %s\n", post.PublishedAt.Time.Format(time.RFC1123))
+	}
+	if post.Author.Valid {
+		fmt.Printf("Author:     %s\n", post.Author.String)
+	}
+	if len(post.Categories) > 0 {
+		fmt.Printf("Categories: %s\n", strings.Join(post.Categories, ", "))
+	}
+	if post.CommentsUrl.Valid {
+		fmt.Printf("Comments:   %s\n", post.CommentsUrl.String)
+	}
+	if post.Season.Valid || post.Episode.Valid {
+		fmt.Printf("Episode:    season %d, episode %d\n", post.Season.Int32, post.Episode.Int32)
+	}
+	if post.DurationSeconds.Valid {
+		fmt.Printf("Duration:   %s\n", time.Duration(post.DurationSeconds.Int32)*time.Second)
+	}
+	if post.ImageUrl.Valid {
+		fmt.Printf("Image:      %s\n", post.ImageUrl.String)
+	}
+	if len(edits) > 0 {
+		fmt.Printf("Updated:    %d times, last on %s\n", len(edits), edits[0].CreatedAt.Format(time.RFC1123))
+	}
+
+	if len(enclosures) > 0 {
+		fmt.Println("Enclosures:")
+		for _, enclosure := range enclosures {
+			var details []string
+			if enclosure.MimeType.Valid {
+				details = append(details, enclosure.MimeType.String)
+			}
+			if enclosure.Length.Valid {
+				details = append(details, fmt.Sprintf("%d bytes", enclosure.Length.Int64))
+			}
+			if enclosure.LocalPath.Valid {
+				details = append(details, "downloaded to "+enclosure.LocalPath.String)
+			}
+			if len(details) > 0 {
+				fmt.Printf("* %s (%s)\n", enclosure.Url, strings.Join(details, ", "))
+			} else {
+				fmt.Printf("* %s\n", enclosure.Url)
+			}
+		}
+	}
+
+	text := rss.PlainText(post.Content.String)
+	if text == "" {
+		text = rss.PlainText(post.Description.String)
+	}
+	if text != "" {
+		fmt.Println()
+		for _, line := range wrapText(text, 80) {
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
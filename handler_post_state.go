@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+func postIDArg(cmd command) (uuid.UUID, error) {
+	if len(cmd.args) != 1 {
+		return uuid.Nil, fmt.Errorf("%s requires a post ID", cmd.command)
+	}
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to parse post ID: %w", err)
+	}
+	return postID, nil
+}
+
+func handlerRead(s *state, cmd command, user database.User) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setPostRead(context.Background(), s.db, user, postID, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked post %s as read\n", postID)
+	return nil
+}
+
+func handlerUnread(s *state, cmd command, user database.User) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setPostRead(context.Background(), s.db, user, postID, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked post %s as unread\n", postID)
+	return nil
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setPostStarred(context.Background(), s.db, user, postID, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Starred post %s\n", postID)
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	postID, err := postIDArg(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setPostStarred(context.Background(), s.db, user, postID, false); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unstarred post %s\n", postID)
+	return nil
+}
+
+func setPostRead(ctx context.Context, db apiStore, user database.User, postID uuid.UUID, read bool) error {
+	if _, err := db.GetPostDetails(ctx, postID); err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if !read {
+		err := db.MarkPostUnread(ctx, database.MarkPostUnreadParams{
+			PostID: postID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark post as unread: %w", err)
+		}
+		return nil
+	}
+
+	err := db.MarkPostRead(ctx, database.MarkPostReadParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		PostID:    postID,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark post as read: %w", err)
+	}
+	return nil
+}
+
+func setPostStarred(ctx context.Context, db apiStore, user database.User, postID uuid.UUID, starred bool) error {
+	if _, err := db.GetPostDetails(ctx, postID); err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if !starred {
+		err := db.UnstarPost(ctx, database.UnstarPostParams{
+			PostID: postID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unstar post: %w", err)
+		}
+		return nil
+	}
+
+	err := db.StarPost(ctx, database.StarPostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		PostID:    postID,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to star post: %w", err)
+	}
+	return nil
+}
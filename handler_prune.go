@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jasonwashburn/gator/internal/config"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+type pruneOptions struct {
+	maxAge        time.Duration
+	maxPerFeed    int
+	includeUnread bool
+}
+
+func pruneOptionsFromConfig(cfg config.PruneConfig) (pruneOptions, error) {
+	opts := pruneOptions{maxPerFeed: cfg.MaxPerFeed}
+	if cfg.MaxAge != "" {
+		maxAge, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return pruneOptions{}, fmt.Errorf("failed to parse prune max age: %w", err)
+		}
+		opts.maxAge = maxAge
+	}
+	return opts, nil
+}
+
+func handlerPrune(s *state, cmd command, _ database.User) error {
+	opts, err := pruneOptionsFromConfig(s.cfg.Prune)
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet(cmd)
+	fs.DurationVar(&opts.maxAge, "max-age", opts.maxAge, "delete posts published longer ago than this")
+	fs.IntVar(&opts.maxPerFeed, "max-per-feed", opts.maxPerFeed, "keep at most this many posts per feed")
+	fs.BoolVar(&opts.includeUnread, "include-unread", false, "also delete posts a follower has not read")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("prune: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("prune does not take any arguments")
+	}
+
+	return prunePosts(context.Background(), s.db, opts)
+}
+
+// prunePosts deletes posts outside the retention policy in opts. Starred
+// posts are always kept, and so are posts a follower of their feed has not
+// read yet unless opts.includeUnread is set.
+func prunePosts(ctx context.Context, db *database.Queries, opts pruneOptions) error {
+	if opts.maxAge <= 0 && opts.maxPerFeed <= 0 {
+		return fmt.Errorf("prune requires a max age or a max number of posts per feed")
+	}
+
+	var olderThan sql.NullTime
+	if opts.maxAge > 0 {
+		olderThan = sql.NullTime{Time: time.Now().Add(-opts.maxAge), Valid: true}
+	}
+
+	pruned, err := db.PrunePosts(ctx, database.PrunePostsParams{
+		OlderThan:  olderThan,
+		MaxPerFeed: int64(opts.maxPerFeed),
+		KeepUnread: !opts.includeUnread,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune posts: %w", err)
+	}
+
+	var total int64
+	for _, feed := range pruned {
+		fmt.Printf("* %s - %s: removed %d posts\n", feed.FeedName, feed.FeedUrl, feed.Removed)
+		total += feed.Removed
+	}
+	fmt.Printf("Pruned %d posts\n", total)
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+func handlerReset(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	posts := fs.Bool("posts", false, "only delete posts")
+	feeds := fs.Bool("feeds", false, "only delete feeds, their follows and posts")
+	userName := fs.String("user", "", "only delete this user")
+	force := fs.Bool("force", false, "allow resetting a database that is not on this machine")
+	backup := fs.String("backup", "", "dump the database to this file with pg_dump first")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("reset does not take any arguments")
+	}
+
+	scopes := 0
+	for _, set := range []bool{*posts, *feeds, *userName != ""} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return fmt.Errorf("reset: --posts, --feeds and --user cannot be combined")
+	}
+
+	target := "all users, feeds and posts"
+	switch {
+	case *posts:
+		target = "all posts"
+	case *feeds:
+		target = "all feeds and their posts"
+	case *userName != "":
+		target = fmt.Sprintf("user %s", *userName)
+	}
+
+	host, local := databaseHost(s.cfg.DbURL)
+	if !local && !*force {
+		return fmt.Errorf("reset: refusing to reset the database on %s; pass --force to reset it anyway", host)
+	}
+
+	if !*yes {
+		fmt.Printf("This will permanently delete %s from the database on %s.\n", target, host)
+		if err := confirm("reset"); err != nil {
+			return err
+		}
+	}
+
+	if *backup != "" {
+		if err := dumpDatabase(s.cfg.DbURL, *backup); err != nil {
+			return err
+		}
+		fmt.Printf("Backed up database to %s\n", *backup)
+	}
+
+	ctx := context.Background()
+	switch {
+	case *posts:
+		n, err := s.db.ResetPosts(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to reset posts: %w", err)
+		}
+		fmt.Printf("Deleted %d posts\n", n)
+	case *feeds:
+		n, err := s.db.ResetFeeds(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to reset feeds: %w", err)
+		}
+		fmt.Printf("Deleted %d feeds\n", n)
+	case *userName != "":
+		if err := deleteUser(ctx, s.conn, *userName); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted user %s\n", *userName)
+	default:
+		feedCount, userCount, err := resetAll(ctx, s.conn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %d users and %d feeds\n", userCount, feedCount)
+	}
+
+	// Deleting the current user also deleted the session in the config file.
+	if *userName == user.Name || scopes == 0 {
+		if err := s.cfg.ClearSession(); err != nil {
+			return fmt.Errorf("failed to clear session: %w", err)
+		}
+	}
+	return nil
+}
+
+// resetAll deletes every feed and user in one transaction and returns how
+// many of each were deleted.
+func resetAll(ctx context.Context, conn *sql.DB) (feedCount, userCount int64, err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := database.New(tx)
+
+	// Feeds outlive the users who added them, so delete them first.
+	feedCount, err = db.ResetFeeds(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to reset feeds: %w", err)
+	}
+	userCount, err = db.ResetUsers(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to reset users: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit reset: %w", err)
+	}
+	return feedCount, userCount, nil
+}
+
+// databaseHost returns the host named in a Postgres connection string, which
+// may be a URL or a list of key=value settings, and whether it is this
+// machine.
+func databaseHost(dbURL string) (string, bool) {
+	var host string
+	if u, err := url.Parse(dbURL); err == nil && u.Scheme != "" {
+		host = u.Hostname()
+		if host == "" {
+			host = u.Query().Get("host")
+		}
+	} else {
+		for _, field := range strings.Fields(dbURL) {
+			if value, ok := strings.CutPrefix(field, "host="); ok {
+				host = strings.Trim(value, "'")
+			}
+		}
+	}
+
+	switch {
+	case host == "":
+		return "localhost", true
+	case host == "localhost", host == "127.0.0.1", host == "::1", strings.HasPrefix(host, "/"):
+		return host, true
+	}
+	return host, false
+}
+
+// splitDatabasePassword removes the password from a Postgres connection
+// string, which may be a URL or a list of key=value settings, and returns it
+// separately.
+func splitDatabasePassword(dbURL string) (string, string) {
+	if u, err := url.Parse(dbURL); err == nil && u.Scheme != "" {
+		var password string
+		if u.User != nil {
+			password, _ = u.User.Password()
+			u.User = url.User(u.User.Username())
+		}
+		query := u.Query()
+		if query.Has("password") {
+			password = query.Get("password")
+			query.Del("password")
+			u.RawQuery = query.Encode()
+		}
+		return u.String(), password
+	}
+
+	var password string
+	var fields []string
+	for _, field := range strings.Fields(dbURL) {
+		if value, ok := strings.CutPrefix(field, "password="); ok {
+			password = strings.Trim(value, "'")
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return strings.Join(fields, " "), password
+}
+
+// dumpDatabase backs up the database to path with pg_dump. The password is
+// passed in the environment, because other local users can read the
+// command line.
+func dumpDatabase(dbURL, path string) error {
+	dbURL, password := splitDatabasePassword(dbURL)
+	pgDump := exec.Command("pg_dump", "--dbname="+dbURL, "--file="+path)
+	if password != "" {
+		pgDump.Env = append(os.Environ(), "PGPASSWORD="+password)
+	}
+	pgDump.Stderr = os.Stderr
+	if err := pgDump.Run(); err != nil {
+		return fmt.Errorf("failed to back up database with pg_dump: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+func handlerTag(s *state, cmd command, user database.User) error {
+	feed, tags, err := tagArgs(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.db.AddFeedFollowTags(context.Background(), database.AddFeedFollowTagsParams{
+		FeedID: feed.ID,
+		UserID: user.ID,
+		Tags:   tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag feed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you do not follow %s", feed.Name)
+	}
+
+	fmt.Printf("Tagged %s with %s\n", feed.Name, strings.Join(tags, ", "))
+	return nil
+}
+
+func handlerUntag(s *state, cmd command, user database.User) error {
+	feed, tags, err := tagArgs(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.db.RemoveFeedFollowTags(context.Background(), database.RemoveFeedFollowTagsParams{
+		FeedID: feed.ID,
+		UserID: user.ID,
+		Tags:   tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to untag feed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you do not follow %s", feed.Name)
+	}
+
+	fmt.Printf("Removed %s from %s\n", strings.Join(tags, ", "), feed.Name)
+	return nil
+}
+
+// tagArgs parses the feed URL and tags given to tag and untag.
+func tagArgs(s *state, cmd command) (database.Feed, []string, error) {
+	if len(cmd.args) < 2 {
+		return database.Feed{}, nil, fmt.Errorf("%s requires a feed URL and at least one tag", cmd.command)
+	}
+	var tags []string
+	for _, tag := range cmd.args[1:] {
+		tag, err := parseTag(tag)
+		if err != nil {
+			return database.Feed{}, nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return database.Feed{}, nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	return feed, tags, nil
+}
+
+// parseTag trims tag and rejects the characters OPML uses to separate
+// categories.
+func parseTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", fmt.Errorf("tags cannot be empty")
+	}
+	if strings.ContainsAny(tag, ",/") {
+		return "", fmt.Errorf("tag %q cannot contain , or /", tag)
+	}
+	return tag, nil
+}
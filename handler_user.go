@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+func handlerUser(s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("user requires a subcommand: rename, delete, set-password or reset-token")
+	}
+
+	sub := command{command: "user " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "rename":
+		return handlerUserRename(s, sub, user)
+	case "delete":
+		return handlerUserDelete(s, sub, user)
+	case "set-password":
+		return handlerUserSetPassword(s, sub, user)
+	case "reset-token":
+		return handlerUserResetToken(s, sub, user)
+	default:
+		return fmt.Errorf("unknown user subcommand: %s", cmd.args[0])
+	}
+}
+
+func handlerUserRename(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("%s requires the current and the new username", cmd.command)
+	}
+	oldName, newName := cmd.args[0], cmd.args[1]
+	if oldName != user.Name && user.Role != roleAdmin {
+		return fmt.Errorf("only admins can rename other users")
+	}
+
+	n, err := s.db.RenameUser(context.Background(), database.RenameUserParams{NewName: newName, OldName: oldName})
+	if database.IsUniqueViolation(err) {
+		return fmt.Errorf("user %s already exists", newName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename user: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("user %s not found", oldName)
+	}
+	if oldName == user.Name {
+		if err := s.cfg.SetSession(newName, s.cfg.SessionToken); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+
+	fmt.Printf("Renamed user %s to %s\n", oldName, newName)
+	return nil
+}
+
+func handlerUserSetPassword(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("%s requires a username", cmd.command)
+	}
+	if user.Role != roleAdmin {
+		return fmt.Errorf("%s requires an admin user", cmd.command)
+	}
+
+	ctx := context.Background()
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", cmd.args[0], err)
+	}
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setPassword(ctx, s.db, target, hash); err != nil {
+		return err
+	}
+	if target.ID == user.ID {
+		if err := s.cfg.ClearSession(); err != nil {
+			return fmt.Errorf("failed to clear session: %w", err)
+		}
+	}
+
+	fmt.Printf("Set the password of %s\n", target.Name)
+	return nil
+}
+
+// handlerUserResetToken issues a one-time token with which a user chooses a
+// new password at login.
+func handlerUserResetToken(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("%s requires a username", cmd.command)
+	}
+	if user.Role != roleAdmin {
+		return fmt.Errorf("%s requires an admin user", cmd.command)
+	}
+
+	ctx := context.Background()
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", cmd.args[0], err)
+	}
+	token, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = s.db.UpsertPasswordReset(ctx, database.UpsertPasswordResetParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(auth.PasswordResetDuration),
+		UserID:    target.ID,
+		TokenHash: auth.HashToken(token),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	fmt.Printf("Reset token for %s, valid for %s:\n%s\n", target.Name, auth.PasswordResetDuration, token)
+	fmt.Printf("They can choose a new password with: gator login --reset %s\n", target.Name)
+	return nil
+}
+
+func handlerUserDelete(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.command, err)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%s requires a username", cmd.command)
+	}
+	if user.Role != roleAdmin {
+		return fmt.Errorf("%s requires an admin user", cmd.command)
+	}
+	name := args[0]
+
+	if !*yes {
+		fmt.Printf("Deleting %s removes their follows, read and starred posts. Feeds they added are kept for their other followers.\n", name)
+		if err := confirm("delete"); err != nil {
+			return err
+		}
+	}
+
+	if err := deleteUser(context.Background(), s.conn, name); err != nil {
+		return err
+	}
+	if name == user.Name {
+		if err := s.cfg.ClearSession(); err != nil {
+			return fmt.Errorf("failed to clear session: %w", err)
+		}
+	}
+
+	fmt.Printf("Deleted user %s\n", name)
+	return nil
+}
+
+// deleteUser deletes a user together with their follows and read state.
+// Feeds they added are handed to their longest-standing other follower, or
+// kept without an owner, so other followers do not lose them. Both happen in
+// one transaction, so a failed delete leaves the feeds with their owner.
+func deleteUser(ctx context.Context, conn *sql.DB, name string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := database.New(tx)
+
+	user, err := db.GetUser(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", name, err)
+	}
+	if err := checkNotLastAdmin(ctx, db, name); err != nil {
+		return err
+	}
+
+	if err := db.TransferFeedOwnership(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to transfer feed ownership: %w", err)
+	}
+	if _, err := db.DeleteUserByName(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+	return nil
+}
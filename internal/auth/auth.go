@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionDuration is how long a session token stays valid after login.
+const SessionDuration = 30 * 24 * time.Hour
+
+// PasswordResetDuration is how long a password reset token can be used.
+const PasswordResetDuration = 24 * time.Hour
+
+// MinPasswordLength is the shortest password HashPassword accepts.
+const MinPasswordLength = 8
+
+var ErrInvalidPassword = errors.New("invalid password")
+
+// HashPassword returns the bcrypt hash stored for password.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns ErrInvalidPassword unless password matches hash.
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrInvalidPassword
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check password: %w", err)
+	}
+	return nil
+}
+
+// NewToken returns a random session token. Only its HashToken digest is
+// stored, so a leaked database does not leak usable sessions.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,8 +6,46 @@ import (
 )
 
 type ConfigFile struct {
-	DbURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbURL           string      `json:"db_url"`
+	CurrentUserName string      `json:"current_user_name"`
+	SessionToken    string      `json:"session_token,omitempty"`
+	Prune           PruneConfig `json:"prune"`
+	PodcastDir      string      `json:"podcast_dir,omitempty"`
+	Fetch           FetchConfig `json:"fetch"`
+	SMTP            SMTPConfig  `json:"smtp"`
+}
+
+// PruneConfig holds the retention policy applied by the prune command and,
+// when Interval is set, periodically by the aggregator.
+type PruneConfig struct {
+	MaxAge     string `json:"max_age,omitempty"`
+	MaxPerFeed int    `json:"max_per_feed,omitempty"`
+	Interval   string `json:"interval,omitempty"`
+}
+
+// FetchConfig holds the HTTP settings the aggregator uses to download feeds.
+// Unset fields keep the fetcher's defaults.
+type FetchConfig struct {
+	Timeout         string   `json:"timeout,omitempty"`
+	MaxBodyBytes    int64    `json:"max_body_bytes,omitempty"`
+	UserAgent       string   `json:"user_agent,omitempty"`
+	Proxy           string   `json:"proxy,omitempty"`
+	CABundle        string   `json:"ca_bundle,omitempty"`
+	MaxConnsPerHost int      `json:"max_conns_per_host,omitempty"`
+	HostDelay       string   `json:"host_delay,omitempty"`
+	RobotsTxt       bool     `json:"robots_txt,omitempty"`
+	TrackingParams  []string `json:"tracking_params,omitempty"`
+}
+
+// SMTPConfig holds the mail server that email digests are sent through.
+// Digests are disabled while Host is unset.
+type SMTPConfig struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from,omitempty"`
+	TLS      string `json:"tls,omitempty"`
 }
 
 const configFileName = ".gatorconfig.json"
@@ -20,6 +58,19 @@ func getConfigFilePath() (string, error) {
 	return userHomeDir + "/" + configFileName, nil
 }
 
+// PodcastDirectory returns the directory podcast episodes are downloaded
+// into, ~/Podcasts unless podcast_dir is set.
+func (c *ConfigFile) PodcastDirectory() (string, error) {
+	if c.PodcastDir != "" {
+		return c.PodcastDir, nil
+	}
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return userHomeDir + "/Podcasts", nil
+}
+
 func Read() (ConfigFile, error) {
 	filepath, err := getConfigFilePath()
 	if err != nil {
@@ -44,11 +95,15 @@ func (c *ConfigFile) write() error {
 	if err != nil {
 		return err
 	}
-	file, err := os.Create(filepath)
+	// The file holds a session token, so keep it private to the user.
+	file, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
 
 	err = json.NewEncoder(file).Encode(c)
 	if err != nil {
@@ -66,3 +121,17 @@ func (c *ConfigFile) SetUser(user string) error {
 	}
 	return nil
 }
+
+// SetSession records the user logged in on this machine and the token of
+// their session.
+func (c *ConfigFile) SetSession(user, token string) error {
+	c.CurrentUserName = user
+	c.SessionToken = token
+	return c.write()
+}
+
+func (c *ConfigFile) ClearSession() error {
+	c.CurrentUserName = ""
+	c.SessionToken = ""
+	return c.write()
+}
@@ -7,19 +7,53 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const addFeedFollowTags = `-- name: AddFeedFollowTags :execrows
+UPDATE feed_follows
+SET tags = ARRAY(SELECT DISTINCT unnest(tags || $3::text[]) ORDER BY 1), updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type AddFeedFollowTagsParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+	Tags   []string
+}
+
+func (q *Queries) AddFeedFollowTags(ctx context.Context, arg AddFeedFollowTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addFeedFollowTags, arg.FeedID, arg.UserID, pq.Array(arg.Tags))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countFeedFollowers = `-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1
+`
+
+func (q *Queries) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowers, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
     INSERT INTO feed_follows (id, created_at, updated_at, feed_id, user_id) 
     VALUES ($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, feed_id, user_id
+    RETURNING id, created_at, updated_at, feed_id, user_id, auto_download_since, tags, display_name, priority, muted
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.feed_id, inserted_feed_follow.user_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.feed_id, inserted_feed_follow.user_id, inserted_feed_follow.auto_download_since, inserted_feed_follow.tags, inserted_feed_follow.display_name, inserted_feed_follow.priority, inserted_feed_follow.muted,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -36,13 +70,18 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	FeedID            uuid.UUID
+	UserID            uuid.UUID
+	AutoDownloadSince sql.NullTime
+	Tags              []string
+	DisplayName       sql.NullString
+	Priority          int32
+	Muted             bool
+	FeedName          string
+	UserName          string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -60,6 +99,11 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.FeedID,
 		&i.UserID,
+		&i.AutoDownloadSince,
+		pq.Array(&i.Tags),
+		&i.DisplayName,
+		&i.Priority,
+		&i.Muted,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -81,22 +125,61 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, feed_id, user_id, auto_download_since, tags, display_name, priority, muted FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type GetFeedFollowParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.FeedID, arg.UserID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.UserID,
+		&i.AutoDownloadSince,
+		pq.Array(&i.Tags),
+		&i.DisplayName,
+		&i.Priority,
+		&i.Muted,
+	)
+	return i, err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id, feeds.name AS feed_name, users.name AS user_name
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id, feed_follows.auto_download_since, feed_follows.tags, feed_follows.display_name, feed_follows.priority, feed_follows.muted,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 INNER JOIN users ON users.id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.priority DESC, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	FeedID            uuid.UUID
+	UserID            uuid.UUID
+	AutoDownloadSince sql.NullTime
+	Tags              []string
+	DisplayName       sql.NullString
+	Priority          int32
+	Muted             bool
+	FeedName          string
+	FeedUrl           string
+	UserName          string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -114,7 +197,13 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.FeedID,
 			&i.UserID,
+			&i.AutoDownloadSince,
+			pq.Array(&i.Tags),
+			&i.DisplayName,
+			&i.Priority,
+			&i.Muted,
 			&i.FeedName,
+			&i.FeedUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -129,3 +218,68 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const removeFeedFollowTags = `-- name: RemoveFeedFollowTags :execrows
+UPDATE feed_follows
+SET tags = ARRAY(SELECT unnest(tags) EXCEPT SELECT unnest($3::text[]) ORDER BY 1), updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type RemoveFeedFollowTagsParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+	Tags   []string
+}
+
+func (q *Queries) RemoveFeedFollowTags(ctx context.Context, arg RemoveFeedFollowTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFeedFollowTags, arg.FeedID, arg.UserID, pq.Array(arg.Tags))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowAutoDownload = `-- name: SetFeedFollowAutoDownload :execrows
+UPDATE feed_follows
+SET auto_download_since = $3, updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type SetFeedFollowAutoDownloadParams struct {
+	FeedID            uuid.UUID
+	UserID            uuid.UUID
+	AutoDownloadSince sql.NullTime
+}
+
+func (q *Queries) SetFeedFollowAutoDownload(ctx context.Context, arg SetFeedFollowAutoDownloadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowAutoDownload, arg.FeedID, arg.UserID, arg.AutoDownloadSince)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :exec
+UPDATE feed_follows
+SET display_name = $3, priority = $4, muted = $5, updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	FeedID      uuid.UUID
+	UserID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedFollowSettings,
+		arg.FeedID,
+		arg.UserID,
+		arg.DisplayName,
+		arg.Priority,
+		arg.Muted,
+	)
+	return err
+}
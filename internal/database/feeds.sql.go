@@ -7,15 +7,28 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const clearFeedRedirect = `-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1 AND redirect_url IS NOT NULL
+`
+
+func (q *Queries) ClearFeedRedirect(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearFeedRedirect, id)
+	return err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url
 `
 
 type CreateFeedParams struct {
@@ -24,7 +37,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
-	UserID    uuid.UUID
+	UserID    uuid.NullUUID
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -45,12 +58,72 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FeverID,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		pq.Array(&i.ParseWarnings),
+		&i.NextFetchAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+	)
+	return i, err
+}
+
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DeactivateFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed, id)
+	return err
+}
+
+const deleteFeedByURL = `-- name: DeleteFeedByURL :execrows
+DELETE FROM feeds
+WHERE url = $1
+`
+
+func (q *Queries) DeleteFeedByURL(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedByURL, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FeverID,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		pq.Array(&i.ParseWarnings),
+		&i.NextFetchAt,
+		&i.HubUrl,
+		&i.TopicUrl,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url FROM feeds
 WHERE url = $1
 LIMIT 1
 `
@@ -66,12 +139,29 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FeverID,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		pq.Array(&i.ParseWarnings),
+		&i.NextFetchAt,
+		&i.HubUrl,
+		&i.TopicUrl,
 	)
 	return i, err
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url FROM feeds
+WHERE deactivated_at IS NULL
+  AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+      AND websub_subscriptions.state = 'active'
+      AND websub_subscriptions.lease_expires_at > NOW()
+      AND feeds.last_fetched_at > NOW() - INTERVAL '1 day'
+  )
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -87,12 +177,20 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FeverID,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		pq.Array(&i.ParseWarnings),
+		&i.NextFetchAt,
+		&i.HubUrl,
+		&i.TopicUrl,
 	)
 	return i, err
 }
 
 const listFeeds = `-- name: ListFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url FROM feeds
 ORDER BY created_at DESC
 `
 
@@ -113,6 +211,65 @@ func (q *Queries) ListFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.FeverID,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeactivatedAt,
+			pq.Array(&i.ParseWarnings),
+			&i.NextFetchAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedsPage = `-- name: ListFeedsPage :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fever_id, redirect_url, redirect_count, deactivated_at, parse_warnings, next_fetch_at, hub_url, topic_url FROM feeds
+ORDER BY created_at DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListFeedsPageParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListFeedsPage(ctx context.Context, arg ListFeedsPageParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedsPage, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FeverID,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeactivatedAt,
+			pq.Array(&i.ParseWarnings),
+			&i.NextFetchAt,
+			&i.HubUrl,
+			&i.TopicUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -129,7 +286,7 @@ func (q *Queries) ListFeeds(ctx context.Context) ([]Feed, error) {
 
 const markFeedAsFetched = `-- name: MarkFeedAsFetched :exec
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET last_fetched_at = NOW(), next_fetch_at = NULL, updated_at = NOW()
 WHERE id = $1
 `
 
@@ -137,3 +294,213 @@ func (q *Queries) MarkFeedAsFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedAsFetched, id)
 	return err
 }
+
+const mergeFeedFollows = `-- name: MergeFeedFollows :exec
+INSERT INTO feed_follows (
+    id, created_at, updated_at, feed_id, user_id, auto_download_since, tags, display_name, priority, muted
+)
+SELECT
+    gen_random_uuid(), created_at, NOW(), $1::uuid, user_id, auto_download_since, tags, display_name, priority, muted
+FROM feed_follows
+WHERE feed_id = $2::uuid
+ON CONFLICT (feed_id, user_id) DO NOTHING
+`
+
+type MergeFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MergeFeedFollows(ctx context.Context, arg MergeFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const mergeFeedPosts = `-- name: MergeFeedPosts :exec
+UPDATE posts
+SET feed_id = $1::uuid
+WHERE posts.feed_id = $2::uuid
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = $1::uuid
+    AND existing.item_key = posts.item_key
+)
+`
+
+type MergeFeedPostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MergeFeedPosts(ctx context.Context, arg MergeFeedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedPosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const mergePostReads = `-- name: MergePostReads :exec
+INSERT INTO post_reads (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), post_reads.created_at, NOW(), existing.id, post_reads.user_id
+FROM post_reads
+INNER JOIN posts ON posts.id = post_reads.post_id
+INNER JOIN posts AS existing ON existing.item_key = posts.item_key
+WHERE posts.feed_id = $2::uuid
+AND existing.feed_id = $1::uuid
+ON CONFLICT (post_id, user_id) DO NOTHING
+`
+
+type MergePostReadsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MergePostReads(ctx context.Context, arg MergePostReadsParams) error {
+	_, err := q.db.ExecContext(ctx, mergePostReads, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const mergePostStars = `-- name: MergePostStars :exec
+INSERT INTO post_stars (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), post_stars.created_at, NOW(), existing.id, post_stars.user_id
+FROM post_stars
+INNER JOIN posts ON posts.id = post_stars.post_id
+INNER JOIN posts AS existing ON existing.item_key = posts.item_key
+WHERE posts.feed_id = $2::uuid
+AND existing.feed_id = $1::uuid
+ON CONFLICT (post_id, user_id) DO NOTHING
+`
+
+type MergePostStarsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MergePostStars(ctx context.Context, arg MergePostStarsParams) error {
+	_, err := q.db.ExecContext(ctx, mergePostStars, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE
+        WHEN redirect_url = $1::text THEN redirect_count + 1
+        ELSE 1
+    END,
+    redirect_url = $1::text,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING redirect_count
+`
+
+type RecordFeedRedirectParams struct {
+	RedirectUrl string
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedRedirect, arg.RedirectUrl, arg.ID)
+	var redirect_count int32
+	err := row.Scan(&redirect_count)
+	return redirect_count, err
+}
+
+const renameFeed = `-- name: RenameFeed :execrows
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE url = $1
+`
+
+type RenameFeedParams struct {
+	Url  string
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameFeed, arg.Url, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetFeeds = `-- name: ResetFeeds :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) ResetFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedNextFetchAt = `-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1
+`
+
+type SetFeedNextFetchAtParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetchAt(ctx context.Context, arg SetFeedNextFetchAtParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetchAt, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const setFeedParseWarnings = `-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET parse_warnings = $2
+WHERE id = $1
+`
+
+type SetFeedParseWarningsParams struct {
+	ID            uuid.UUID
+	ParseWarnings []string
+}
+
+func (q *Queries) SetFeedParseWarnings(ctx context.Context, arg SetFeedParseWarningsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarnings, arg.ID, pq.Array(arg.ParseWarnings))
+	return err
+}
+
+const setFeedURL = `-- name: SetFeedURL :execrows
+UPDATE feeds
+SET url = $1,
+    redirect_url = NULL,
+    redirect_count = 0,
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE url = $2
+`
+
+type SetFeedURLParams struct {
+	NewUrl string
+	OldUrl string
+}
+
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedURL, arg.NewUrl, arg.OldUrl)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeedOwnership = `-- name: TransferFeedOwnership :exec
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = NOW()
+WHERE feeds.user_id = $1
+`
+
+func (q *Queries) TransferFeedOwnership(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, transferFeedOwnership, userID)
+	return err
+}
@@ -11,38 +11,158 @@ import (
 	"github.com/google/uuid"
 )
 
+type DigestSchedule struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Email      string
+	Frequency  string
+	LastSentAt sql.NullTime
+	Failures   int32
+	RetryAt    sql.NullTime
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	Name          string
 	Url           string
-	UserID        uuid.UUID
+	UserID        uuid.NullUUID
 	LastFetchedAt sql.NullTime
+	FeverID       int64
+	RedirectUrl   sql.NullString
+	RedirectCount int32
+	DeactivatedAt sql.NullTime
+	ParseWarnings []string
+	NextFetchAt   sql.NullTime
+	HubUrl        sql.NullString
+	TopicUrl      sql.NullString
 }
 
 type FeedFollow struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	FeedID            uuid.UUID
+	UserID            uuid.UUID
+	AutoDownloadSince sql.NullTime
+	Tags              []string
+	DisplayName       sql.NullString
+	Priority          int32
+	Muted             bool
+}
+
+type Filter struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	Regex     bool
+	MarkRead  bool
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
 }
 
 type Post struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	ItemKey         string
+	ContentHash     string
+	Summary         sql.NullString
+	Content         sql.NullString
+	Author          sql.NullString
+	Categories      []string
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+	FeverID         int64
+}
+
+type PostEdit struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	PostID      uuid.UUID
 	Title       string
-	Url         string
 	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ContentHash string
 }
 
-type User struct {
+type PostEnclosure struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	PostID           uuid.UUID
+	Url              string
+	MimeType         sql.NullString
+	Length           sql.NullInt64
+	DownloadedAt     sql.NullTime
+	LocalPath        sql.NullString
+	DownloadFailures int32
+	DownloadRetryAt  sql.NullTime
+}
+
+type PostRead struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	UserID    uuid.UUID
+}
+
+type PostStar struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Name      string
+	PostID    uuid.UUID
+	UserID    uuid.UUID
+}
+
+type Session struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+}
+
+type User struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	FeverApiKey   sql.NullString
+	PasswordHash  sql.NullString
+	Role          string
+	FeedTokenHash sql.NullString
+}
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	State          string
+	LeaseExpiresAt sql.NullTime
+	RenewAt        sql.NullTime
 }
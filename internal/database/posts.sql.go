@@ -11,36 +11,69 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptPost = `-- name: AdoptPost :one
+UPDATE posts
+SET item_key = $1
+WHERE id = (
+    SELECT id FROM posts AS legacy
+    WHERE legacy.feed_id = $2
+    AND legacy.item_key = ANY($3::text[])
+    ORDER BY legacy.created_at
+    LIMIT 1
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, item_key, content_hash, summary, content, author, categories, comments_url, duration_seconds, episode, season, image_url, fever_id
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type AdoptPostParams struct {
+	ItemKey    string
+	FeedID     uuid.UUID
+	LegacyKeys []string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
+func (q *Queries) AdoptPost(ctx context.Context, arg AdoptPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, adoptPost, arg.ItemKey, arg.FeedID, pq.Array(arg.LegacyKeys))
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ItemKey,
+		&i.ContentHash,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.ImageUrl,
+		&i.FeverID,
 	)
+	return i, err
+}
+
+const getPostByItemKey = `-- name: GetPostByItemKey :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, item_key, content_hash, summary, content, author, categories, comments_url, duration_seconds, episode, season, image_url, fever_id FROM posts
+WHERE feed_id = $1 AND item_key = $2
+LIMIT 1
+`
+
+type GetPostByItemKeyParams struct {
+	FeedID  uuid.UUID
+	ItemKey string
+}
+
+func (q *Queries) GetPostByItemKey(ctx context.Context, arg GetPostByItemKeyParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByItemKey, arg.FeedID, arg.ItemKey)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -51,33 +84,183 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.ItemKey,
+		&i.ContentHash,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.ImageUrl,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getPostDetails = `-- name: GetPostDetails :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.item_key, posts.content_hash, posts.summary, posts.content, posts.author, posts.categories, posts.comments_url, posts.duration_seconds, posts.episode, posts.season, posts.image_url, posts.fever_id, feeds.name AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id = $1
+`
+
+type GetPostDetailsRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	ItemKey         string
+	ContentHash     string
+	Summary         sql.NullString
+	Content         sql.NullString
+	Author          sql.NullString
+	Categories      []string
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+	FeverID         int64
+	FeedName        string
+}
+
+func (q *Queries) GetPostDetails(ctx context.Context, id uuid.UUID) (GetPostDetailsRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostDetails, id)
+	var i GetPostDetailsRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ItemKey,
+		&i.ContentHash,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.ImageUrl,
+		&i.FeverID,
+		&i.FeedName,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
-WHERE feed_id IN (
-    SELECT id FROM feeds WHERE user_id = $1
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.item_key, posts.content_hash, posts.summary, posts.content, posts.author, posts.categories, posts.comments_url, posts.duration_seconds, posts.episode, posts.season, posts.image_url, posts.fever_id,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    EXISTS (SELECT 1 FROM post_edits WHERE post_edits.post_id = posts.id) AS updated,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+    ) AS read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1
+    ) AS starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND (
+    NOT $2::boolean
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+    )
 )
-ORDER BY published_at DESC
-LIMIT $2
+AND ($3::uuid IS NULL OR posts.feed_id = $3::uuid)
+AND ($4::text IS NULL OR $4::text = ANY(feed_follows.tags))
+AND (
+    NOT $5::boolean
+    OR (
+        NOT feed_follows.muted
+        AND NOT EXISTS (
+            SELECT 1 FROM filters
+            WHERE filters.user_id = $1 AND post_matches_filter(posts, filters)
+        )
+    )
+)
+ORDER BY
+    CASE WHEN $6::boolean THEN feed_follows.priority ELSE 0 END DESC,
+    posts.published_at DESC
+LIMIT $7
+OFFSET $8
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID       uuid.UUID
+	UnreadOnly   bool
+	FeedID       uuid.NullUUID
+	Tag          sql.NullString
+	ApplyFilters bool
+	ByPriority   bool
+	Limit        int32
+	Offset       int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForUserRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	ItemKey         string
+	ContentHash     string
+	Summary         sql.NullString
+	Content         sql.NullString
+	Author          sql.NullString
+	Categories      []string
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+	FeverID         int64
+	FeedName        string
+	FeedUrl         string
+	Updated         bool
+	Read            bool
+	Starred         bool
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.FeedID,
+		arg.Tag,
+		arg.ApplyFilters,
+		arg.ByPriority,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -87,6 +270,23 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ItemKey,
+			&i.ContentHash,
+			&i.Summary,
+			&i.Content,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.CommentsUrl,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.ImageUrl,
+			&i.FeverID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Updated,
+			&i.Read,
+			&i.Starred,
 		); err != nil {
 			return nil, err
 		}
@@ -100,3 +300,191 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const prunePosts = `-- name: PrunePosts :many
+WITH ranked AS (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (
+            PARTITION BY feed_id
+            ORDER BY published_at DESC NULLS LAST, created_at DESC
+        ) AS position
+    FROM posts
+),
+pruned AS (
+    DELETE FROM posts
+    USING ranked
+    WHERE posts.id = ranked.id
+    AND (
+        COALESCE(posts.published_at, posts.created_at) < $1::timestamp
+        OR ($2::bigint > 0 AND ranked.position > $2::bigint)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id
+    )
+    AND (
+        NOT $3::boolean
+        OR NOT EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_reads
+                WHERE post_reads.post_id = posts.id
+                AND post_reads.user_id = feed_follows.user_id
+            )
+        )
+    )
+    RETURNING posts.feed_id
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS removed
+FROM pruned
+INNER JOIN feeds ON feeds.id = pruned.feed_id
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name
+`
+
+type PrunePostsParams struct {
+	OlderThan  sql.NullTime
+	MaxPerFeed int64
+	KeepUnread bool
+}
+
+type PrunePostsRow struct {
+	FeedName string
+	FeedUrl  string
+	Removed  int64
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) ([]PrunePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, prunePosts, arg.OlderThan, arg.MaxPerFeed, arg.KeepUnread)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PrunePostsRow
+	for rows.Next() {
+		var i PrunePostsRow
+		if err := rows.Scan(&i.FeedName, &i.FeedUrl, &i.Removed); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetPosts = `-- name: ResetPosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) ResetPosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetPosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    id, created_at, updated_at, title, url, description, published_at, feed_id,
+    item_key, content_hash, summary, content, author, categories, comments_url,
+    duration_seconds, episode, season, image_url
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+    $11, $12, $13, $14, $15, $16, $17, $18, $19
+)
+ON CONFLICT (feed_id, item_key) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    summary = EXCLUDED.summary,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    categories = EXCLUDED.categories,
+    comments_url = EXCLUDED.comments_url,
+    duration_seconds = EXCLUDED.duration_seconds,
+    episode = EXCLUDED.episode,
+    season = EXCLUDED.season,
+    image_url = EXCLUDED.image_url,
+    content_hash = EXCLUDED.content_hash,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, item_key, content_hash, summary, content, author, categories, comments_url, duration_seconds, episode, season, image_url, fever_id
+`
+
+type UpsertPostParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.UUID
+	ItemKey         string
+	ContentHash     string
+	Summary         sql.NullString
+	Content         sql.NullString
+	Author          sql.NullString
+	Categories      []string
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.ItemKey,
+		arg.ContentHash,
+		arg.Summary,
+		arg.Content,
+		arg.Author,
+		pq.Array(arg.Categories),
+		arg.CommentsUrl,
+		arg.DurationSeconds,
+		arg.Episode,
+		arg.Season,
+		arg.ImageUrl,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ItemKey,
+		&i.ContentHash,
+		&i.Summary,
+		&i.Content,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.ImageUrl,
+		&i.FeverID,
+	)
+	return i, err
+}
@@ -7,27 +7,55 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin'
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsersWithPassword = `-- name: CountUsersWithPassword :one
+SELECT COUNT(*) FROM users
+WHERE password_hash IS NOT NULL
+`
+
+func (q *Queries) CountUsersWithPassword(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithPassword)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'user' ELSE 'admin' END
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, fever_api_key, password_hash, role, feed_token_hash
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -36,6 +64,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.PasswordHash,
 	)
 	var i User
 	err := row.Scan(
@@ -43,12 +72,29 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.FeverApiKey,
+		&i.PasswordHash,
+		&i.Role,
+		&i.FeedTokenHash,
 	)
 	return i, err
 }
 
+const deleteUserByName = `-- name: DeleteUserByName :execrows
+DELETE FROM users
+WHERE name = $1
+`
+
+func (q *Queries) DeleteUserByName(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserByName, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, fever_api_key, password_hash, role, feed_token_hash FROM users
 WHERE name = $1 LIMIT 1
 `
 
@@ -60,12 +106,16 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.FeverApiKey,
+		&i.PasswordHash,
+		&i.Role,
+		&i.FeedTokenHash,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, fever_api_key, password_hash, role, feed_token_hash FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -77,12 +127,16 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.FeverApiKey,
+		&i.PasswordHash,
+		&i.Role,
+		&i.FeedTokenHash,
 	)
 	return i, err
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, fever_api_key, password_hash, role, feed_token_hash FROM users
 ORDER BY id
 `
 
@@ -100,6 +154,54 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.FeverApiKey,
+			&i.PasswordHash,
+			&i.Role,
+			&i.FeedTokenHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT id, created_at, updated_at, name, fever_api_key, password_hash, role, feed_token_hash FROM users
+ORDER BY name
+LIMIT $1
+OFFSET $2
+`
+
+type ListUsersPageParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersPage, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.FeverApiKey,
+			&i.PasswordHash,
+			&i.Role,
+			&i.FeedTokenHash,
 		); err != nil {
 			return nil, err
 		}
@@ -114,11 +216,84 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const resetUsers = `-- name: ResetUsers :exec
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users
+SET name = $1, fever_api_key = NULL, updated_at = NOW()
+WHERE name = $2
+`
+
+type RenameUserParams struct {
+	NewName string
+	OldName string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser, arg.NewName, arg.OldName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetUsers = `-- name: ResetUsers :execrows
 DELETE FROM users
 `
 
-func (q *Queries) ResetUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, resetUsers)
+func (q *Queries) ResetUsers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetUsers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserFeedToken = `-- name: SetUserFeedToken :exec
+UPDATE users
+SET feed_token_hash = $2
+WHERE id = $1
+`
+
+type SetUserFeedTokenParams struct {
+	ID            uuid.UUID
+	FeedTokenHash sql.NullString
+}
+
+func (q *Queries) SetUserFeedToken(ctx context.Context, arg SetUserFeedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, setUserFeedToken, arg.ID, arg.FeedTokenHash)
 	return err
 }
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE name = $1
+`
+
+type SetUserRoleParams struct {
+	Name string
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Name, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
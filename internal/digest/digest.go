@@ -0,0 +1,153 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const sinceLayout = "Mon, 02 Jan 2006 15:04 MST"
+
+// Digest is an email listing the new posts of the feeds a user follows.
+type Digest struct {
+	Since time.Time
+	Feeds []Feed
+}
+
+type Feed struct {
+	Name  string
+	Posts []Post
+}
+
+type Post struct {
+	Title   string
+	URL     string
+	Summary string
+}
+
+// Len returns the number of posts in the digest.
+func (d Digest) Len() int {
+	n := 0
+	for _, feed := range d.Feeds {
+		n += len(feed.Posts)
+	}
+	return n
+}
+
+func (d Digest) subject() string {
+	noun := "posts"
+	if d.Len() == 1 {
+		noun = "post"
+	}
+	return fmt.Sprintf("gator digest: %d new %s since %s", d.Len(), noun, d.Since.Format(sinceLayout))
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("digest").Parse(
+	`New posts since {{.Since.Format "` + sinceLayout + `"}}
+{{range .Feeds}}
+== {{.Name}} ==
+{{range .Posts}}
+* {{.Title}}
+  {{.URL}}
+{{- if .Summary}}
+
+  {{.Summary}}
+{{- end}}
+{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<p>New posts since {{.Since.Format "` + sinceLayout + `"}}</p>
+{{range .Feeds}}
+<h2>{{.Name}}</h2>
+<ul>
+{{range .Posts}}
+<li>
+<a href="{{.URL}}">{{.Title}}</a>
+{{if .Summary}}<p>{{.Summary}}</p>{{end}}
+</li>
+{{end}}
+</ul>
+{{end}}
+</body>
+</html>
+`))
+
+// Message renders the digest as an email from from to to, with a plaintext
+// and an HTML version of the same content.
+func (d Digest) Message(from, to string, now time.Time) ([]byte, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	toAddr, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	if err := writePart(parts, "text/plain", func(buf *bytes.Buffer) error {
+		return textTemplate.Execute(buf, d)
+	}); err != nil {
+		return nil, err
+	}
+	if err := writePart(parts, "text/html", func(buf *bytes.Buffer) error {
+		return htmlTemplate.Execute(buf, d)
+	}); err != nil {
+		return nil, err
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	_, domain, _ := strings.Cut(fromAddr.Address, "@")
+	var msg bytes.Buffer
+	headers := []string{
+		"From: " + fromAddr.String(),
+		"To: " + toAddr.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", d.subject()),
+		"Date: " + now.Format(time.RFC1123Z),
+		"Message-ID: <" + uuid.NewString() + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	for _, header := range headers {
+		msg.WriteString(header + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// writePart adds a quoted-printable UTF-8 part of contentType rendered by
+// render.
+func writePart(parts *multipart.Writer, contentType string, render func(*bytes.Buffer) error) error {
+	var content bytes.Buffer
+	if err := render(&content); err != nil {
+		return fmt.Errorf("failed to render %s digest: %w", contentType, err)
+	}
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(content.Bytes()); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,128 @@
+package digest
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDigest = Digest{
+	Since: time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC),
+	Feeds: []Feed{
+		{Name: "Go Blog", Posts: []Post{
+			{Title: "Go 1.27 is released", URL: "https://go.dev/blog/go1.27", Summary: "Today the Go team released Go 1.27."},
+			{Title: "Range over <func>", URL: "https://go.dev/blog/range-functions"},
+		}},
+		{Name: "Café Notes", Posts: []Post{
+			{Title: "Crème brûlée", URL: "https://example.com/creme"},
+		}},
+	},
+}
+
+// readMessage parses msg and returns its headers and the decoded body of
+// each part by content type.
+func readMessage(t *testing.T, msg []byte) (mail.Header, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", m.Header.Get("Content-Type"))
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		// NextPart decodes quoted-printable parts.
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return m.Header, parts
+}
+
+func TestDigestMessage(t *testing.T) {
+	now := time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC)
+	msg, err := testDigest.Message("gator <gator@example.com>", "me@example.com", now)
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+	header, parts := readMessage(t, msg)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decoding subject: %v", err)
+	}
+	if want := "gator digest: 3 new posts since Thu, 01 Oct 2026 08:00 UTC"; subject != want {
+		t.Errorf("Subject = %q, want %q", subject, want)
+	}
+	if got := header.Get("From"); got != `"gator" <gator@example.com>` {
+		t.Errorf("From = %q", got)
+	}
+	if got := header.Get("To"); got != "<me@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if date, err := header.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %q, want %s", header.Get("Date"), now)
+	}
+	if id := header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{"== Go Blog ==", "* Go 1.27 is released", "https://go.dev/blog/go1.27", "Today the Go team released Go 1.27.", "== Café Notes ==", "* Crème brûlée"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part is missing %q:\n%s", want, text)
+		}
+	}
+	html := parts["text/html"]
+	for _, want := range []string{"<h2>Go Blog</h2>", `<a href="https://go.dev/blog/go1.27">Go 1.27 is released</a>`, "Range over &lt;func&gt;", "<h2>Café Notes</h2>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML part is missing %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestDigestMessageInvalidAddress(t *testing.T) {
+	if _, err := testDigest.Message("not an address", "me@example.com", time.Now()); err == nil {
+		t.Error("Message accepted an invalid sender")
+	}
+	if _, err := testDigest.Message("gator@example.com", "", time.Now()); err == nil {
+		t.Error("Message accepted an empty recipient")
+	}
+}
+
+func TestDigestSentOverSMTP(t *testing.T) {
+	f := startFakeSMTP(t)
+	msg, err := testDigest.Message("gator@example.com", "me@example.com", time.Now())
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+	if err := f.mailer(TLSNone).Send("gator@example.com", []string{"me@example.com"}, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-f.done
+
+	// The server sees the message with its line endings normalized and dot
+	// stuffing undone.
+	_, parts := readMessage(t, []byte(f.data))
+	if !strings.Contains(parts["text/plain"], "* Crème brûlée") {
+		t.Errorf("delivered text part is missing a post:\n%s", parts["text/plain"])
+	}
+}
@@ -0,0 +1,103 @@
+package digest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+	TLSNone     = "none"
+
+	smtpTimeout = time.Minute
+)
+
+// SMTP is the mail server digests are sent through.
+type SMTP struct {
+	Host string
+	// Port defaults to 465 with implicit TLS and 587 otherwise.
+	Port     int
+	Username string
+	Password string
+	// TLS is TLSStartTLS, the default, to upgrade the connection with
+	// STARTTLS, TLSImplicit to connect over TLS, or TLSNone to send in
+	// plaintext. Send fails when STARTTLS is required but not offered.
+	TLS string
+}
+
+// Send delivers msg from from to each of to.
+func (s SMTP) Send(from string, to []string, msg []byte) error {
+	port := s.Port
+	if port == 0 {
+		port = 587
+		if s.TLS == TLSImplicit {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	switch s.TLS {
+	case TLSImplicit:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case "", TLSStartTLS, TLSNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return fmt.Errorf("unknown SMTP TLS mode %q", s.TLS)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if s.TLS == "" || s.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not offer STARTTLS; set smtp.tls to %q to send without encryption", addr, TLSNone)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted
+		// connection to anything but localhost.
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+	return client.Quit()
+}
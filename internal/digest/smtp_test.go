@@ -0,0 +1,139 @@
+package digest
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP is an SMTP server that accepts every message and records the
+// last one.
+type fakeSMTP struct {
+	ln         net.Listener
+	extensions []string
+
+	mu   sync.Mutex
+	auth string
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func startFakeSMTP(t *testing.T, extensions ...string) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{ln: ln, extensions: extensions, done: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		defer close(f.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		f.serve(textproto.NewConn(conn))
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn *textproto.Conn) {
+	conn.PrintfLine("220 localhost fake ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		f.mu.Lock()
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			for _, ext := range f.extensions {
+				conn.PrintfLine("250-%s", ext)
+			}
+			conn.PrintfLine("250 localhost")
+		case "AUTH":
+			f.auth = arg
+			conn.PrintfLine("235 authenticated")
+		case "MAIL":
+			f.from = arg
+			conn.PrintfLine("250 ok")
+		case "RCPT":
+			f.to = append(f.to, arg)
+			conn.PrintfLine("250 ok")
+		case "DATA":
+			conn.PrintfLine("354 go ahead")
+			lines, err := conn.ReadDotLines()
+			if err != nil {
+				f.mu.Unlock()
+				return
+			}
+			f.data = strings.Join(lines, "\n")
+			conn.PrintfLine("250 queued")
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			f.mu.Unlock()
+			return
+		default:
+			conn.PrintfLine("502 not implemented")
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeSMTP) mailer(tlsMode string) SMTP {
+	return SMTP{Host: "127.0.0.1", Port: f.ln.Addr().(*net.TCPAddr).Port, TLS: tlsMode}
+}
+
+func TestSMTPSend(t *testing.T) {
+	f := startFakeSMTP(t, "AUTH PLAIN")
+	mailer := f.mailer(TLSNone)
+	mailer.Username = "gator"
+	mailer.Password = "s3cret"
+
+	msg := "Subject: hi\r\n\r\nhello\r\n"
+	if err := mailer.Send("gator@example.com", []string{"a@example.com", "b@example.com"}, []byte(msg)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-f.done
+
+	if !strings.HasPrefix(f.auth, "PLAIN ") {
+		t.Errorf("AUTH %q, want PLAIN", f.auth)
+	}
+	if f.from != "FROM:<gator@example.com>" {
+		t.Errorf("MAIL %q", f.from)
+	}
+	if len(f.to) != 2 || f.to[0] != "TO:<a@example.com>" || f.to[1] != "TO:<b@example.com>" {
+		t.Errorf("RCPT %q", f.to)
+	}
+	if f.data != "Subject: hi\n\nhello" {
+		t.Errorf("DATA %q", f.data)
+	}
+}
+
+func TestSMTPSendRequiresSTARTTLS(t *testing.T) {
+	for _, mode := range []string{"", TLSStartTLS} {
+		f := startFakeSMTP(t)
+		err := f.mailer(mode).Send("gator@example.com", []string{"a@example.com"}, []byte("hello\r\n"))
+		if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+			t.Errorf("TLS %q: Send = %v, want a STARTTLS error", mode, err)
+		}
+		f.ln.Close()
+		<-f.done
+		if f.from != "" || f.data != "" {
+			t.Errorf("TLS %q: sent a message without encryption", mode)
+		}
+	}
+}
+
+func TestSMTPSendUnknownTLSMode(t *testing.T) {
+	err := SMTP{Host: "127.0.0.1", Port: 1, TLS: "ssl"}.Send("gator@example.com", []string{"a@example.com"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown SMTP TLS mode") {
+		t.Fatalf("Send = %v, want an unknown mode error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package podcast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Download fetches rawURL into dest. The body is written to dest+".part"
+// first, and an existing partial file is resumed with an HTTP range request,
+// so an interrupted download picks up where it stopped. A partial file is
+// discarded if the server answers with another range. It returns the size of
+// the finished file.
+func Download(ctx context.Context, client *http.Client, rawURL, dest string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return 0, err
+	}
+
+	partial := dest + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			if offset == 0 {
+				return 0, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+			}
+			// The server sent another part of the file than the one
+			// asked for, so appending it would corrupt the download.
+			resp.Body.Close()
+			if err := os.Remove(partial); err != nil {
+				return 0, err
+			}
+			return Download(ctx, client, rawURL, dest)
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// The server ignored the range, so start over.
+		flags |= os.O_TRUNC
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return 0, fmt.Errorf("status code: %d", resp.StatusCode)
+		}
+		// The partial file already holds the whole body.
+		return offset, os.Rename(partial, dest)
+	default:
+		return 0, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(partial, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(partial, dest); err != nil {
+		return 0, err
+	}
+	return offset + written, nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header such as "bytes 100-199/200", or -1 if it cannot be parsed.
+func contentRangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// FileName returns a file name for an episode: its title made safe for use
+// in a path and id, which tells apart episodes with the same title, with the
+// extension of the enclosure URL.
+func FileName(title, id, rawURL string) string {
+	ext := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		ext = path.Ext(u.Path)
+	}
+
+	name := SafeName(title)
+	if name == "" {
+		name = SafeName(strings.TrimSuffix(path.Base(rawURL), ext))
+	}
+	if name == "" {
+		name = "episode"
+	}
+	return name + " " + SafeName(id) + ext
+}
+
+// SafeName replaces characters that are not portable in file names.
+func SafeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '-'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if runes := []rune(name); len(runes) > 120 {
+		name = string(runes[:120])
+	}
+	return name
+}
@@ -0,0 +1,117 @@
+package podcast
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const episode = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// download resumes a download of the handler's episode from a partial file
+// holding the first offset bytes.
+func download(t *testing.T, handler http.HandlerFunc, offset int) string {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "episode.mp3")
+	if offset > 0 {
+		if err := os.WriteFile(dest+".part", []byte(episode[:offset]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size, err := Download(context.Background(), ts.Client(), ts.URL+"/episode.mp3", dest)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	body, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(body)) {
+		t.Errorf("Download returned size %d, file has %d bytes", size, len(body))
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+	return string(body)
+}
+
+func serveEpisode(w http.ResponseWriter, r *http.Request) {
+	http.ServeContent(w, r, "episode.mp3", time.Time{}, strings.NewReader(episode))
+}
+
+func TestDownload(t *testing.T) {
+	if got := download(t, serveEpisode, 0); got != episode {
+		t.Errorf("downloaded %q, want %q", got, episode)
+	}
+}
+
+func TestDownloadResumes(t *testing.T) {
+	var ranges []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		serveEpisode(w, r)
+	}
+	if got := download(t, handler, 10); got != episode {
+		t.Errorf("downloaded %q, want %q", got, episode)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=10-" {
+		t.Errorf("requested ranges %q, want [bytes=10-]", ranges)
+	}
+}
+
+func TestDownloadRangeIgnored(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(episode))
+	}
+	if got := download(t, handler, 10); got != episode {
+		t.Errorf("downloaded %q, want %q", got, episode)
+	}
+}
+
+func TestDownloadRangeMismatch(t *testing.T) {
+	var ranges []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") == "" {
+			serveEpisode(w, r)
+			return
+		}
+		// Answer with a range starting before the one asked for.
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 5-%d/%d", len(episode)-1, len(episode)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(episode[5:]))
+	}
+	if got := download(t, handler, 10); got != episode {
+		t.Errorf("downloaded %q, want %q", got, episode)
+	}
+	if len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("requested ranges %q, want a restart without a range", ranges)
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+	}{
+		{"bytes 100-199/200", 100},
+		{"bytes 0-99/*", 0},
+		{"bytes */200", -1},
+		{"items 1-2/3", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := contentRangeStart(tt.header); got != tt.want {
+			t.Errorf("contentRangeStart(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
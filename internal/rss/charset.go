@@ -0,0 +1,103 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var utf8BOM = []byte("\ufeff")
+
+var xmlEncodingDecl = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// toUTF8 converts a feed body to UTF-8. Its encoding is taken from a byte
+// order mark, else the charset of the Content-Type header, else the XML
+// declaration. A body without a known encoding is expected to be UTF-8; when
+// it is not valid UTF-8 it is decoded as Windows-1252, which is what most
+// mislabelled feeds turn out to be. A body labelled with a single-byte
+// encoding that is valid UTF-8 is kept as UTF-8, since text in those
+// encodings hardly ever forms valid multibyte sequences.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	label := bomCharset(body)
+	if label == "" {
+		label = contentTypeCharset(contentType)
+	}
+	if label == "" {
+		label = declaredCharset(body)
+	}
+
+	enc, name := charset.Lookup(label)
+	if enc == nil || name == "utf-8" {
+		body = bytes.TrimPrefix(body, utf8BOM)
+		if utf8.Valid(body) {
+			return body, nil
+		}
+		enc = charmap.Windows1252
+	} else if singleByte(name) && utf8.Valid(body) {
+		return body, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(decoded, utf8BOM), nil
+}
+
+// singleByte reports whether name, as returned by charset.Lookup, is one of
+// the single-byte encodings of the WHATWG Encoding Standard.
+func singleByte(name string) bool {
+	switch name {
+	case "ibm866", "macintosh", "x-mac-cyrillic":
+		return true
+	}
+	return strings.HasPrefix(name, "iso-8859-") || strings.HasPrefix(name, "koi8-") || strings.HasPrefix(name, "windows-")
+}
+
+func bomCharset(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	}
+	return ""
+}
+
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+func declaredCharset(body []byte) string {
+	if len(body) > 1024 {
+		body = body[:1024]
+	}
+	if m := xmlEncodingDecl.FindSubmatch(body); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// unmarshalXML decodes a body already converted by toUTF8. Its XML
+// declaration may still name the original encoding, which the decoder would
+// otherwise refuse.
+func unmarshalXML(body []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder.Decode(v)
+}
@@ -0,0 +1,93 @@
+package rss
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestToUTF8Fixtures(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		title       string
+		item        string
+	}{
+		{"iso-8859-1.xml", "application/rss+xml", "Café crème", "Über façades"},
+		{"windows-1252.xml", "application/rss+xml", "“Smart” quotes", "Price: 5 €"},
+		{"shift_jis.xml", "application/rss+xml", "日本語のフィード", "こんにちは世界"},
+		{"utf-16le-bom.xml", "application/rss+xml", "Ünïcödé ✓", "Grüße aus Köln"},
+		// The byte order mark wins over a wrong Content-Type charset.
+		{"utf-16le-bom.xml", "text/xml; charset=iso-8859-1", "Ünïcödé ✓", "Grüße aus Köln"},
+		// Declared as ISO-8859-1, but written as UTF-8.
+		{"mislabeled-utf-8.xml", "application/rss+xml", "Café crème", "Über façades"},
+		{"mislabeled-utf-8.xml", "text/xml; charset=windows-1252", "Café crème", "Über façades"},
+		// Not declared at all, and not valid UTF-8.
+		{"undeclared-windows-1252.xml", "application/rss+xml", "Café “crème”", "Über façades"},
+		// The Content-Type charset wins over the XML declaration.
+		{"undeclared-windows-1252.xml", "text/xml; charset=windows-1252", "Café “crème”", "Über façades"},
+		{"iso-8859-1.xml", "text/xml; charset=ISO-8859-1", "Café crème", "Über façades"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file+" "+tt.contentType, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := toUTF8(body, tt.contentType)
+			if err != nil {
+				t.Fatalf("toUTF8: %v", err)
+			}
+			if !utf8.Valid(decoded) {
+				t.Fatal("toUTF8 returned invalid UTF-8")
+			}
+
+			feed, err := parseFeed(decoded)
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if len(feed.Warnings) != 0 {
+				t.Errorf("parseFeed warnings: %q", feed.Warnings)
+			}
+			if feed.Channel.Title != tt.title {
+				t.Errorf("channel title = %q, want %q", feed.Channel.Title, tt.title)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+			if feed.Channel.Item[0].Title != tt.item {
+				t.Errorf("item title = %q, want %q", feed.Channel.Item[0].Title, tt.item)
+			}
+		})
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"utf-8", "<a>Grüße</a>", "", "<a>Grüße</a>"},
+		{"utf-8 bom", "\ufeff<a>Grüße</a>", "", "<a>Grüße</a>"},
+		{"undeclared latin-1", "<a>Gr\xfc\xdfe</a>", "", "<a>Grüße</a>"},
+		{"utf-8 labelled latin-1", "<a>Grüße</a>", "text/xml; charset=iso-8859-1", "<a>Grüße</a>"},
+		{"ascii labelled latin-1", "<a>hello</a>", "text/xml; charset=iso-8859-1", "<a>hello</a>"},
+		{"unknown label", "<a>Grüße</a>", "text/xml; charset=x-unknown", "<a>Grüße</a>"},
+		{"declared gb2312", "<?xml version=\"1.0\" encoding=\"GB2312\"?><a>\xd6\xd0\xce\xc4</a>", "", "<?xml version=\"1.0\" encoding=\"GB2312\"?><a>中文</a>"},
+		{"utf-16be bom", "\xfe\xff\x00<\x00a\x00>\x00\xfc\x00<\x00/\x00a\x00>", "", "<a>ü</a>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8([]byte(tt.body), tt.contentType)
+			if err != nil {
+				t.Fatalf("toUTF8: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toUTF8 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Timeline is a list of posts republished as a single feed.
+type Timeline struct {
+	ID          string
+	Title       string
+	Description string
+	Link        string
+	SelfURL     string
+	Updated     time.Time
+	Items       []TimelineItem
+}
+
+type TimelineItem struct {
+	ID         string
+	Title      string
+	Link       string
+	Summary    string
+	Content    string
+	Author     string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+	Source     TimelineSource
+}
+
+// TimelineSource is the feed an item was originally published in.
+type TimelineSource struct {
+	Title string
+	URL   string
+}
+
+type rssOutput struct {
+	XMLName      xml.Name         `xml:"rss"`
+	Version      string           `xml:"version,attr"`
+	XMLNSContent string           `xml:"xmlns:content,attr"`
+	XMLNSDC      string           `xml:"xmlns:dc,attr"`
+	XMLNSAtom    string           `xml:"xmlns:atom,attr"`
+	Channel      rssOutputChannel `xml:"channel"`
+}
+
+type rssOutputChannel struct {
+	Title         string          `xml:"title"`
+	Link          string          `xml:"link"`
+	Description   string          `xml:"description"`
+	SelfLink      *rssOutputLink  `xml:"atom:link,omitempty"`
+	LastBuildDate string          `xml:"lastBuildDate,omitempty"`
+	Items         []rssOutputItem `xml:"item"`
+}
+
+type rssOutputLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssOutputItem struct {
+	Title       string           `xml:"title"`
+	Link        string           `xml:"link,omitempty"`
+	GUID        rssOutputGUID    `xml:"guid"`
+	Description string           `xml:"description,omitempty"`
+	Content     *cdata           `xml:"content:encoded,omitempty"`
+	Creator     string           `xml:"dc:creator,omitempty"`
+	Categories  []string         `xml:"category"`
+	PubDate     string           `xml:"pubDate,omitempty"`
+	Source      *rssOutputSource `xml:"source,omitempty"`
+}
+
+type rssOutputGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssOutputSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type atomOutput struct {
+	XMLName  xml.Name          `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string            `xml:"id"`
+	Title    string            `xml:"title"`
+	Subtitle string            `xml:"subtitle,omitempty"`
+	Updated  string            `xml:"updated"`
+	Links    []atomOutputLink  `xml:"link"`
+	Entries  []atomOutputEntry `xml:"entry"`
+}
+
+type atomOutputLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomOutputEntry struct {
+	ID         string            `xml:"id"`
+	Title      string            `xml:"title"`
+	Links      []atomOutputLink  `xml:"link"`
+	Published  string            `xml:"published,omitempty"`
+	Updated    string            `xml:"updated"`
+	Authors    []atomPerson      `xml:"author"`
+	Categories []atomCategory    `xml:"category"`
+	Summary    *atomOutputText   `xml:"summary,omitempty"`
+	Content    *atomOutputText   `xml:"content,omitempty"`
+	Source     *atomOutputSource `xml:"source,omitempty"`
+}
+
+type atomOutputText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomOutputSource struct {
+	Title string           `xml:"title"`
+	Links []atomOutputLink `xml:"link"`
+}
+
+// WriteRSS writes t to w as an RSS 2.0 document.
+func WriteRSS(w io.Writer, t Timeline) error {
+	doc := rssOutput{
+		Version:      "2.0",
+		XMLNSContent: "http://purl.org/rss/1.0/modules/content/",
+		XMLNSDC:      "http://purl.org/dc/elements/1.1/",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		Channel: rssOutputChannel{
+			Title:       t.Title,
+			Link:        t.Link,
+			Description: t.Description,
+		},
+	}
+	if t.SelfURL != "" {
+		doc.Channel.SelfLink = &rssOutputLink{Href: t.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	if !t.Updated.IsZero() {
+		doc.Channel.LastBuildDate = t.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, item := range t.Items {
+		out := rssOutputItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssOutputGUID{Value: item.ID},
+			Description: item.Summary,
+			Creator:     item.Author,
+			Categories:  item.Categories,
+		}
+		if item.Content != "" {
+			out.Content = &cdata{Value: item.Content}
+		}
+		if !item.Published.IsZero() {
+			out.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+		}
+		if item.Source.URL != "" {
+			out.Source = &rssOutputSource{URL: item.Source.URL, Title: item.Source.Title}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, out)
+	}
+
+	return writeXML(w, doc)
+}
+
+// WriteAtom writes t to w as an Atom 1.0 document.
+func WriteAtom(w io.Writer, t Timeline) error {
+	doc := atomOutput{
+		ID:       t.ID,
+		Title:    t.Title,
+		Subtitle: t.Description,
+		Updated:  atomTime(t.Updated),
+	}
+	if t.Link != "" {
+		doc.Links = append(doc.Links, atomOutputLink{Href: t.Link, Rel: "alternate", Type: "text/html"})
+	}
+	if t.SelfURL != "" {
+		doc.Links = append(doc.Links, atomOutputLink{Href: t.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for _, item := range t.Items {
+		updated := item.Updated
+		if updated.IsZero() {
+			updated = item.Published
+		}
+		entry := atomOutputEntry{
+			ID:      item.ID,
+			Title:   item.Title,
+			Updated: atomTime(updated),
+		}
+		if item.Link != "" {
+			entry.Links = []atomOutputLink{{Href: item.Link, Rel: "alternate"}}
+		}
+		if !item.Published.IsZero() {
+			entry.Published = atomTime(item.Published)
+		}
+		if item.Author != "" {
+			entry.Authors = []atomPerson{{Name: item.Author}}
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomOutputText{Type: "html", Value: item.Summary}
+		}
+		if item.Content != "" {
+			entry.Content = &atomOutputText{Type: "html", Value: item.Content}
+		}
+		if item.Source.URL != "" {
+			entry.Source = &atomOutputSource{
+				Title: item.Source.Title,
+				Links: []atomOutputLink{{Href: item.Source.URL, Rel: "self"}},
+			}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return writeXML(w, doc)
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+	return nil
+}
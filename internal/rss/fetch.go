@@ -0,0 +1,297 @@
+package rss
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	DefaultFetchTimeout = 30 * time.Second
+	DefaultMaxBodyBytes = 10 << 20
+	DefaultUserAgent    = "gator/1.0 (+https://github.com/jasonwashburn/gator)"
+)
+
+// ErrFeedGone is returned by FetchFeed when the server answers 410 Gone,
+// meaning the feed has been removed for good.
+var ErrFeedGone = errors.New("feed is gone")
+
+// FetcherOptions configures a Fetcher. Zero values select the defaults.
+type FetcherOptions struct {
+	// Timeout bounds each request, including reading the body.
+	Timeout time.Duration
+	// MaxBodyBytes is the largest decoded feed body that is read.
+	MaxBodyBytes int64
+	UserAgent    string
+	// Proxy is the URL of the proxy used for all requests. When empty the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy string
+	// CABundle is a PEM file of certificates trusted in addition to the
+	// system roots.
+	CABundle string
+	// MaxConnsPerHost limits the connections open to a single host; zero
+	// means no limit.
+	MaxConnsPerHost int
+	// HostDelay is the minimum time between two requests to the same host.
+	HostDelay time.Duration
+	// RobotsTxt makes the fetcher skip feeds that robots.txt disallows for
+	// gator, and honour its Crawl-delay.
+	RobotsTxt bool
+	// TrackingParams replaces DefaultTrackingParams as the query parameters
+	// removed from post links.
+	TrackingParams []string
+}
+
+// Fetcher downloads feeds over HTTP. It is safe for concurrent use.
+type Fetcher struct {
+	transport      *http.Transport
+	timeout        time.Duration
+	maxBodyBytes   int64
+	userAgent      string
+	limiter        *hostLimiter
+	robotsTxt      bool
+	trackingParams []string
+
+	mu     sync.Mutex
+	robots map[string]*robotsRules
+}
+
+func NewFetcher(opts FetcherOptions) (*Fetcher, error) {
+	f := &Fetcher{
+		timeout:        opts.Timeout,
+		maxBodyBytes:   opts.MaxBodyBytes,
+		userAgent:      opts.UserAgent,
+		robotsTxt:      opts.RobotsTxt,
+		robots:         map[string]*robotsRules{},
+		trackingParams: opts.TrackingParams,
+	}
+	if f.timeout <= 0 {
+		f.timeout = DefaultFetchTimeout
+	}
+	if f.maxBodyBytes <= 0 {
+		f.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if f.userAgent == "" {
+		f.userAgent = DefaultUserAgent
+	}
+	if f.trackingParams == nil {
+		f.trackingParams = DefaultTrackingParams
+	}
+	hostDelay := opts.HostDelay
+	if hostDelay <= 0 {
+		hostDelay = DefaultHostDelay
+	}
+	f.limiter = newHostLimiter(hostDelay)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Responses are decoded in readBody, which also handles brotli.
+	transport.DisableCompression = true
+	transport.MaxConnsPerHost = opts.MaxConnsPerHost
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.CABundle != "" {
+		pem, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	f.transport = transport
+	return f, nil
+}
+
+// FetchFeed downloads and parses the feed at feedURL. When the feed was
+// reached only through permanent redirects (301 or 308), it also returns the
+// URL it moved to; otherwise movedTo is empty.
+func (f *Fetcher) FetchFeed(ctx context.Context, feedURL string) (feed *RSSFeed, movedTo string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+
+	redirected, permanent := false, true
+	client := &http.Client{
+		Transport: f.transport,
+		Timeout:   f.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			redirected = true
+			switch req.Response.StatusCode {
+			case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			default:
+				permanent = false
+			}
+			return nil
+		},
+	}
+
+	if f.robotsTxt {
+		allowed, err := f.allowedByRobots(ctx, req.URL)
+		if err != nil {
+			return nil, "", err
+		}
+		if !allowed {
+			return nil, "", ErrRobotsDisallowed
+		}
+	}
+	if err := f.limiter.wait(ctx, req.URL.Host); err != nil {
+		return nil, "", err
+	}
+
+	fmt.Printf("Fetching feed: %s\n", feedURL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, "", ErrFeedGone
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if until, ok := retryAfter(resp, time.Now()); ok {
+			host := resp.Request.URL.Host
+			f.limiter.backOff(host, until)
+			return nil, "", &RetryAfterError{Host: host, Until: until}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	if redirected && permanent {
+		movedTo = resp.Request.URL.String()
+	}
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	feed, err = f.parse(body, resp.Header.Get("Content-Type"), resp.Request.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	return feed, movedTo, nil
+}
+
+// ParseFeed parses a feed document that was not fetched by FetchFeed, such
+// as one pushed by a WebSub hub, exactly as FetchFeed would. Relative links
+// are resolved against feedURL.
+func (f *Fetcher) ParseFeed(body []byte, contentType, feedURL string) (*RSSFeed, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed URL: %w", err)
+	}
+	return f.parse(body, contentType, u)
+}
+
+func (f *Fetcher) parse(body []byte, contentType string, feedURL *url.URL) (*RSSFeed, error) {
+	body, err := toUTF8(body, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode feed: %w", err)
+	}
+
+	feed, err := parseFeed(body)
+	if err != nil {
+		return nil, err
+	}
+
+	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
+	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	for i := range feed.Channel.Item {
+		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
+		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+	}
+	resolveLinks(feed, feedURL, f.trackingParams)
+	return feed, nil
+}
+
+// Client returns an HTTP client with the fetcher's transport, timeout and
+// User-Agent, for other requests made on behalf of feeds.
+func (f *Fetcher) Client() *http.Client {
+	return &http.Client{
+		Transport: userAgentTransport{base: f.transport, userAgent: f.userAgent},
+		Timeout:   f.timeout,
+	}
+}
+
+// userAgentTransport sets the User-Agent of requests that do not have one.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// MaxBodyBytes is the largest feed body the fetcher accepts.
+func (f *Fetcher) MaxBodyBytes() int64 {
+	return f.maxBodyBytes
+}
+
+// readBody decodes the response body according to its Content-Encoding and
+// reads at most maxBodyBytes of the result, so a small compressed response
+// cannot expand into an unbounded one.
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	var r io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip body: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case "br":
+		r = brotli.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > f.maxBodyBytes {
+		return nil, fmt.Errorf("feed is larger than %d bytes", f.maxBodyBytes)
+	}
+	return body, nil
+}
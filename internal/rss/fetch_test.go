@@ -0,0 +1,33 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetcherClientUserAgent(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	for _, tt := range []struct{ option, want string }{
+		{"", DefaultUserAgent},
+		{"my-reader/2.0", "my-reader/2.0"},
+	} {
+		fetcher, err := NewFetcher(FetcherOptions{UserAgent: tt.option})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := fetcher.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got != tt.want {
+			t.Errorf("User-Agent = %q, want %q", got, tt.want)
+		}
+	}
+}
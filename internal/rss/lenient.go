@@ -0,0 +1,187 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var (
+	cdataStart = []byte("<![CDATA[")
+	cdataEnd   = []byte("]]>")
+	entityRef  = regexp.MustCompile(`^&(?:[A-Za-z][A-Za-z0-9]*|#[0-9]+|#[xX][0-9A-Fa-f]+);`)
+
+	// documentStarts are the ways a feed document can begin; anything before
+	// the first of them is not part of the feed.
+	documentStarts = [][]byte{[]byte("<?xml"), []byte("<rss"), []byte("<feed"), []byte("<rdf:RDF")}
+
+	// autoClose lists the empty HTML elements that unescaped markup in a
+	// description may leave open. Unlike xml.HTMLAutoClose it leaves out
+	// link, which has content in RSS.
+	autoClose = []string{"basefont", "br", "area", "img", "param", "hr", "input", "col", "frame", "isindex", "base", "meta"}
+)
+
+// parseFeedLenient recovers what it can from a document the XML decoder
+// rejected. Text before the document is dropped, bare ampersands are
+// escaped, HTML entities are accepted and unclosed elements are closed.
+// Items are decoded one at a time, so an error part way through keeps the
+// items before it. It returns nil if nothing could be recovered, along with
+// a description of each problem it worked around.
+func parseFeedLenient(body []byte) (*RSSFeed, []string) {
+	var warnings []string
+	body, skipped := trimLeadingGarbage(body)
+	if skipped > 0 {
+		warnings = append(warnings, fmt.Sprintf("ignored %d bytes before the document", skipped))
+	}
+	body, escaped := escapeBareAmpersands(body)
+	if escaped > 0 {
+		warnings = append(warnings, fmt.Sprintf("escaped %d bare ampersands", escaped))
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = autoClose
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var feed RSSFeed
+	var atom atomFeed
+	isAtom := false
+	var path []string
+
+tokens:
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("stopped parsing early: %s", err))
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(path) == 0 {
+				isAtom = t.Name.Local == "feed"
+				feed.Base = xmlBase(t.Attr)
+				atom.Base = feed.Base
+			}
+			if !isAtom && t.Name.Local == "channel" {
+				feed.Channel.Base = xmlBase(t.Attr)
+			}
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+
+			// Elements decoded here are consumed up to their end tag and
+			// never enter path.
+			var err error
+			switch {
+			case isAtom && t.Name.Local == "entry":
+				var entry atomEntry
+				if err = decoder.DecodeElement(&entry, &t); err == nil {
+					atom.Entries = append(atom.Entries, entry)
+				}
+			case isAtom && parent == "feed" && t.Name.Local == "title":
+				err = decoder.DecodeElement(&atom.Title, &t)
+			case isAtom && parent == "feed" && t.Name.Local == "subtitle":
+				err = decoder.DecodeElement(&atom.Subtitle, &t)
+			case isAtom && parent == "feed" && t.Name.Local == "link":
+				var link atomLink
+				if err = decoder.DecodeElement(&link, &t); err == nil {
+					atom.Links = append(atom.Links, link)
+				}
+			case !isAtom && t.Name.Local == "item":
+				var item RSSItem
+				if err = decoder.DecodeElement(&item, &t); err == nil {
+					feed.Channel.Item = append(feed.Channel.Item, item)
+				}
+			case !isAtom && parent == "channel" && t.Name.Space == atomNamespace && t.Name.Local == "link":
+				var link atomLink
+				if err = decoder.DecodeElement(&link, &t); err == nil {
+					feed.Channel.AtomLinks = append(feed.Channel.AtomLinks, link)
+				}
+			case !isAtom && parent == "channel" && t.Name.Space == "" && t.Name.Local == "title":
+				err = decoder.DecodeElement(&feed.Channel.Title, &t)
+			case !isAtom && parent == "channel" && t.Name.Space == "" && t.Name.Local == "link":
+				err = decoder.DecodeElement(&feed.Channel.Link, &t)
+			case !isAtom && parent == "channel" && t.Name.Space == "" && t.Name.Local == "description":
+				err = decoder.DecodeElement(&feed.Channel.Description, &t)
+			default:
+				path = append(path, t.Name.Local)
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("stopped parsing early: %s", err))
+				break tokens
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+
+	if isAtom {
+		feed = *atom.toRSS()
+	}
+	if feed.Channel.Title == "" && len(feed.Channel.Item) == 0 {
+		return nil, warnings
+	}
+	return &feed, warnings
+}
+
+// trimLeadingGarbage drops whatever precedes the start of the feed document,
+// such as a PHP warning or a stray byte order mark. Leading whitespace is
+// dropped without being counted.
+func trimLeadingGarbage(body []byte) ([]byte, int) {
+	start := -1
+	for _, prefix := range documentStarts {
+		if i := bytes.Index(body, prefix); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	if start <= 0 {
+		return body, 0
+	}
+	if len(bytes.TrimSpace(body[:start])) == 0 {
+		return body[start:], 0
+	}
+	return body[start:], start
+}
+
+// escapeBareAmpersands escapes every & that does not start an entity or
+// character reference, leaving CDATA sections untouched. It returns the
+// number of ampersands escaped.
+func escapeBareAmpersands(body []byte) ([]byte, int) {
+	var out bytes.Buffer
+	out.Grow(len(body))
+	escaped := 0
+	for i := 0; i < len(body); {
+		if bytes.HasPrefix(body[i:], cdataStart) {
+			end := bytes.Index(body[i:], cdataEnd)
+			if end < 0 {
+				out.Write(body[i:])
+				break
+			}
+			end += i + len(cdataEnd)
+			out.Write(body[i:end])
+			i = end
+			continue
+		}
+
+		if body[i] == '&' && !entityRef.Match(body[i:min(i+32, len(body))]) {
+			out.WriteString("&amp;")
+			escaped++
+		} else {
+			out.WriteByte(body[i])
+		}
+		i++
+	}
+	return out.Bytes(), escaped
+}
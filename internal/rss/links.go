@@ -0,0 +1,161 @@
+package rss
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+const (
+	xmlNamespace  = "http://www.w3.org/XML/1998/namespace"
+	atomNamespace = "http://www.w3.org/2005/Atom"
+)
+
+// DefaultTrackingParams are the query parameters removed from post links
+// when no others are configured. A trailing * matches any parameter starting
+// with what precedes it.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi",
+}
+
+// resolveLinks makes the links of feed absolute and normalizes post links.
+// Links in an item are resolved against its xml:base, else the channel's
+// xml:base, else the channel link, else feedURL, the URL the feed was
+// fetched from. Enclosure and image URLs are only resolved, since their
+// query often carries a signature.
+func resolveLinks(feed *RSSFeed, feedURL *url.URL, trackingParams []string) {
+	base := feedURL
+	if u := resolveRef(base, feed.Base); u != nil {
+		base = u
+	}
+	if u := resolveRef(base, feed.Channel.Base); u != nil {
+		base = u
+	}
+	for i, link := range feed.Channel.AtomLinks {
+		if u := resolveRef(base, link.Href); u != nil {
+			feed.Channel.AtomLinks[i].Href = u.String()
+		}
+	}
+	itemBase := base
+	if site := resolveRef(base, feed.Channel.Link); site != nil {
+		feed.Channel.Link = normalizeURL(site, trackingParams)
+		if feed.Base == "" && feed.Channel.Base == "" {
+			itemBase = site
+		}
+	}
+
+	for i := range feed.Channel.Item {
+		item := &feed.Channel.Item[i]
+		item.RawLink = item.Link
+		base := itemBase
+		if u := resolveRef(base, item.Base); u != nil {
+			base = u
+		}
+		if u := resolveRef(base, item.Link); u != nil {
+			item.Link = normalizeURL(u, trackingParams)
+		}
+		if u := resolveRef(base, item.Comments); u != nil {
+			item.Comments = normalizeURL(u, trackingParams)
+		}
+		if u := resolveRef(base, item.Image.Href); u != nil {
+			item.Image.Href = u.String()
+		}
+		for j := range item.Enclosures {
+			if u := resolveRef(base, item.Enclosures[j].URL); u != nil {
+				item.Enclosures[j].URL = u.String()
+			}
+		}
+	}
+}
+
+// resolveRef resolves ref against base. It returns nil for an empty or
+// unparsable ref.
+func resolveRef(base *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	if base == nil {
+		return u
+	}
+	return base.ResolveReference(u)
+}
+
+// normalizeURL canonicalizes an http(s) URL so that links to the same page
+// compare equal: the host is lowercased, default ports and trailing slashes
+// are dropped, an empty path becomes / and tracking parameters are removed.
+func normalizeURL(u *url.URL, trackingParams []string) string {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return u.String()
+	}
+
+	n := *u
+	n.Host = strings.ToLower(n.Host)
+	switch port := n.Port(); {
+	case n.Scheme == "http" && port == "80", n.Scheme == "https" && port == "443":
+		n.Host = strings.TrimSuffix(n.Host, ":"+port)
+	}
+
+	if n.Path == "" {
+		n.Path, n.RawPath = "/", ""
+	} else if len(n.Path) > 1 {
+		n.Path = strings.TrimRight(n.Path, "/")
+		n.RawPath = strings.TrimRight(n.RawPath, "/")
+		if n.Path == "" {
+			n.Path, n.RawPath = "/", ""
+		}
+	}
+
+	n.RawQuery = stripTrackingParams(n.RawQuery, trackingParams)
+	n.ForceQuery = false
+	return n.String()
+}
+
+// stripTrackingParams removes tracking parameters from a raw query, keeping
+// the order and encoding of the others.
+func stripTrackingParams(rawQuery string, trackingParams []string) string {
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !isTrackingParam(key, trackingParams) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func isTrackingParam(key string, trackingParams []string) bool {
+	key = strings.ToLower(key)
+	for _, param := range trackingParams {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlBase returns the xml:base attribute among attrs.
+func xmlBase(attrs []xml.Attr) string {
+	for _, attr := range attrs {
+		if attr.Name.Space == xmlNamespace && attr.Name.Local == "base" {
+			return attr.Value
+		}
+	}
+	return ""
+}
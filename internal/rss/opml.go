@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Subscription is a followed feed listed in an OPML document.
+type Subscription struct {
+	Title string
+	URL   string
+	Tags  []string
+}
+
+type opmlOutput struct {
+	XMLName xml.Name       `xml:"opml"`
+	Version string         `xml:"version,attr"`
+	Head    opmlOutputHead `xml:"head"`
+	Body    opmlOutputBody `xml:"body"`
+}
+
+type opmlOutputBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutputHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// WriteOPML writes subs to w as an OPML 2.0 subscription list. Each tag
+// becomes a folder holding the feeds tagged with it, so a feed with several
+// tags appears in several folders, and untagged feeds are listed at the top
+// level. The category attribute of each feed also lists all of its tags.
+func WriteOPML(w io.Writer, title string, created time.Time, subs []Subscription) error {
+	doc := opmlOutput{
+		Version: "2.0",
+		Head: opmlOutputHead{
+			Title:       title,
+			DateCreated: created.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	var tags []string
+	folders := map[string]*opmlOutline{}
+	var untagged []opmlOutline
+	for _, sub := range subs {
+		outline := opmlOutline{Text: sub.Title, Title: sub.Title, Type: "rss", XMLURL: sub.URL}
+		if len(sub.Tags) == 0 {
+			untagged = append(untagged, outline)
+			continue
+		}
+		categories := make([]string, len(sub.Tags))
+		for i, tag := range sub.Tags {
+			categories[i] = "/" + tag
+		}
+		outline.Category = strings.Join(categories, ",")
+
+		for _, tag := range sub.Tags {
+			folder, ok := folders[tag]
+			if !ok {
+				folder = &opmlOutline{Text: tag, Title: tag}
+				folders[tag] = folder
+				tags = append(tags, tag)
+			}
+			folder.Outlines = append(folder.Outlines, outline)
+		}
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		doc.Body.Outlines = append(doc.Body.Outlines, *folders[tag])
+	}
+	doc.Body.Outlines = append(doc.Body.Outlines, untagged...)
+	return writeXML(w, doc)
+}
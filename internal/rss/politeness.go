@@ -0,0 +1,116 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHostDelay = time.Second
+	// defaultRetryAfter is how long a host that answers 429 without a
+	// Retry-After header is left alone.
+	defaultRetryAfter = 10 * time.Minute
+	// maxRetryAfter caps the wait a host can ask for.
+	maxRetryAfter = 24 * time.Hour
+)
+
+// RetryAfterError is returned by FetchFeed when the feed's host has asked,
+// with 429 Too Many Requests or 503 Service Unavailable, not to be contacted
+// again before Until.
+type RetryAfterError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s asked to retry after %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// hostLimiter spaces out requests to the same host and remembers hosts that
+// asked to be left alone for a while.
+type hostLimiter struct {
+	mu         sync.Mutex
+	delay      time.Duration
+	crawlDelay map[string]time.Duration
+	next       map[string]time.Time
+	retryAfter map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		delay:      delay,
+		crawlDelay: map[string]time.Duration{},
+		next:       map[string]time.Time{},
+		retryAfter: map[string]time.Time{},
+	}
+}
+
+// wait blocks until a request to host may be made. If the host asked to be
+// left alone, it returns a *RetryAfterError instead of waiting.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	if until, ok := l.retryAfter[host]; ok {
+		if until.After(now) {
+			l.mu.Unlock()
+			return &RetryAfterError{Host: host, Until: until}
+		}
+		delete(l.retryAfter, host)
+	}
+	start := l.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	l.next[host] = start.Add(max(l.delay, l.crawlDelay[host]))
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setCrawlDelay makes requests to host at least delay apart, if that is
+// longer than the limiter's own delay.
+func (l *hostLimiter) setCrawlDelay(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.crawlDelay[host] = delay
+}
+
+func (l *hostLimiter) backOff(host string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.retryAfter[host] = until
+}
+
+// retryAfter returns when a 429 or 503 response allows the next request.
+// Retry-After may be given in seconds or as an HTTP date. A 503 without it
+// is treated as an ordinary error.
+func retryAfter(resp *http.Response, now time.Time) (time.Time, bool) {
+	var until time.Time
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		until = now.Add(time.Duration(seconds) * time.Second)
+	} else if t, err := http.ParseTime(value); err == nil {
+		until = t
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		until = now.Add(defaultRetryAfter)
+	} else {
+		return time.Time{}, false
+	}
+
+	if until.After(now.Add(maxRetryAfter)) {
+		until = now.Add(maxRetryAfter)
+	}
+	return until, true
+}
@@ -0,0 +1,98 @@
+package rss
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+const (
+	maxRedirects = 10
+	// movedThreshold is how many fetches in a row must be permanently
+	// redirected to the same URL before the feed's URL is updated, so a
+	// misconfigured server does not move a feed by accident.
+	movedThreshold = 3
+)
+
+// followRedirect records that feed was permanently redirected to movedTo
+// and, once that has happened movedThreshold times in a row, moves the feed
+// to its new URL. If another feed already has that URL the two are merged.
+// It returns the ID of the feed the fetched items belong to.
+func followRedirect(ctx context.Context, conn *sql.DB, db *database.Queries, feed database.Feed, movedTo string) (uuid.UUID, error) {
+	if movedTo == "" || movedTo == feed.Url {
+		if err := db.ClearFeedRedirect(ctx, feed.ID); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to clear feed redirect: %w", err)
+		}
+		return feed.ID, nil
+	}
+
+	count, err := db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		RedirectUrl: movedTo,
+		ID:          feed.ID,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record feed redirect: %w", err)
+	}
+	if count < movedThreshold {
+		return feed.ID, nil
+	}
+
+	target, err := db.GetFeedByURL(ctx, movedTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err := db.SetFeedURL(ctx, database.SetFeedURLParams{NewUrl: movedTo, OldUrl: feed.Url})
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to move feed: %w", err)
+		}
+		fmt.Printf("Feed %s moved to %s\n", feed.Url, movedTo)
+		return feed.ID, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	if err := mergeFeeds(ctx, conn, feed, target); err != nil {
+		return uuid.Nil, err
+	}
+	fmt.Printf("Feed %s moved to %s, merged it into %s\n", feed.Url, movedTo, target.Name)
+	return target.ID, nil
+}
+
+// mergeFeeds moves the followers and posts of from to into, then deletes
+// from, all in one transaction. Posts both feeds have are kept as they are
+// in into, and take over the read and saved state of their duplicates.
+func mergeFeeds(ctx context.Context, conn *sql.DB, from, into database.Feed) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := database.New(tx)
+
+	err = db.MergeFeedFollows(ctx, database.MergeFeedFollowsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+	if err != nil {
+		return fmt.Errorf("failed to merge feed follows: %w", err)
+	}
+	err = db.MergePostReads(ctx, database.MergePostReadsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+	if err != nil {
+		return fmt.Errorf("failed to merge read posts: %w", err)
+	}
+	err = db.MergePostStars(ctx, database.MergePostStarsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+	if err != nil {
+		return fmt.Errorf("failed to merge saved posts: %w", err)
+	}
+	err = db.MergeFeedPosts(ctx, database.MergeFeedPostsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+	if err != nil {
+		return fmt.Errorf("failed to merge posts: %w", err)
+	}
+	if _, err := db.DeleteFeedByURL(ctx, from.Url); err != nil {
+		return fmt.Errorf("failed to delete merged feed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
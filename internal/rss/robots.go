@@ -0,0 +1,189 @@
+package rss
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// robotsAgent is the product token gator looks for in robots.txt.
+	robotsAgent = "gator"
+	robotsTTL   = 24 * time.Hour
+	// robotsRetry is how soon a robots.txt that could not be fetched is
+	// tried again. Until then the host's feeds are fetched.
+	robotsRetry  = time.Hour
+	maxRobotsLen = 512 << 10
+)
+
+// ErrRobotsDisallowed is returned by FetchFeed when robots.txt checking is
+// enabled and the feed's host does not allow gator to fetch it.
+var ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	expires    time.Time
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// allowed applies the most specific rule matching path, preferring Allow
+// when an Allow and a Disallow rule are equally specific.
+func (r *robotsRules) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > best || (rule.length == best && rule.allow) {
+			best, allow = rule.length, rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots reads the rules robots.txt sets for agent, falling back to the
+// rules for * when no group names agent.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var specific, wildcard robotsRules
+	foundSpecific := false
+	var group []*robotsRules
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// Consecutive user-agent lines share the rules that follow.
+			if !inAgents {
+				group = nil
+			}
+			inAgents = true
+			switch {
+			case strings.EqualFold(value, agent):
+				group = append(group, &specific)
+				foundSpecific = true
+			case value == "*":
+				group = append(group, &wildcard)
+			}
+			continue
+		}
+		inAgents = false
+
+		switch key {
+		case "allow", "disallow":
+			// An empty Disallow allows everything, as having no rule does.
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", length: len(value), pattern: robotsPattern(value)}
+			for _, rules := range group {
+				rules.rules = append(rules.rules, rule)
+			}
+		case "crawl-delay":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			for _, rules := range group {
+				rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	if foundSpecific {
+		return &specific
+	}
+	return &wildcard
+}
+
+// robotsPattern compiles a robots.txt path, in which * matches any run of
+// characters and a trailing $ anchors the end of the path.
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(path), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowedByRobots reports whether robots.txt on the host of u lets gator
+// fetch u. Each host's robots.txt is cached for a day.
+func (f *Fetcher) allowedByRobots(ctx context.Context, u *url.URL) (bool, error) {
+	origin := u.Scheme + "://" + u.Host
+	f.mu.Lock()
+	rules, ok := f.robots[origin]
+	f.mu.Unlock()
+
+	if !ok || time.Now().After(rules.expires) {
+		var err error
+		rules, err = f.fetchRobots(ctx, u)
+		if err != nil {
+			return false, err
+		}
+		f.mu.Lock()
+		f.robots[origin] = rules
+		f.mu.Unlock()
+		f.limiter.setCrawlDelay(u.Host, rules.crawlDelay)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return rules.allowed(path), nil
+}
+
+// fetchRobots downloads robots.txt from the host of u. A host without one,
+// or whose robots.txt cannot be fetched, allows everything.
+func (f *Fetcher) fetchRobots(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	if err := f.limiter.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+
+	resp, err := f.Client().Do(req)
+	if err != nil {
+		return &robotsRules{expires: time.Now().Add(robotsRetry)}, nil
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{expires: time.Now().Add(robotsRetry)}, nil
+	case resp.StatusCode != http.StatusOK:
+		return &robotsRules{expires: time.Now().Add(robotsTTL)}, nil
+	}
+
+	rules := parseRobots(io.LimitReader(resp.Body, maxRobotsLen), robotsAgent)
+	rules.expires = time.Now().Add(robotsTTL)
+	return rules, nil
+}
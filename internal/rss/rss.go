@@ -2,12 +2,15 @@ package rss
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"html"
-	"io"
-	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,61 +18,330 @@ import (
 )
 
 type RSSFeed struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+		// AtomLinks holds the atom:link elements of an RSS channel, or the
+		// links of an Atom feed. It comes before Link so that Link only
+		// receives the RSS element.
+		AtomLinks   []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Title       string     `xml:"title"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Item        []RSSItem  `xml:"item"`
 	} `xml:"channel"`
+
+	// Warnings describes what was wrong with a malformed document that
+	// could still be parsed.
+	Warnings []string `xml:"-"`
 }
 
 type RSSItem struct {
+	Base        string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	GUID        string `xml:"guid"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+
+	Author     string         `xml:"author"`
+	Creator    string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories []string       `xml:"category"`
+	Comments   string         `xml:"comments"`
+	Enclosures []RSSEnclosure `xml:"enclosure"`
+
+	Duration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Season   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	Image    ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
 
 	PubDate string `xml:"pubDate"`
+
+	// RawLink is Link as the feed gave it, before it was resolved and
+	// normalized.
+	RawLink string `xml:"-"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
-	if err != nil {
-		return nil, err
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type atomFeed struct {
+	Base     string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type atomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// alternateLink returns the href of the rel="alternate" link, which is also
+// what a link without a rel attribute means in Atom.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
 	}
-	req.Header.Set("User-Agent", "gator")
+	return ""
+}
 
-	fmt.Printf("Fetching feed: %s\n", feedURL)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+func relLink(links []atomLink, rel string) string {
+	for _, link := range links {
+		if link.Rel == rel {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// WebSubLinks returns the WebSub hub the feed announces and the topic URL to
+// subscribe to at that hub, which is the feed's self link. Both are empty if
+// the feed does not announce a hub.
+func (feed *RSSFeed) WebSubLinks() (hub, topic string) {
+	hub = relLink(feed.Channel.AtomLinks, "hub")
+	if hub == "" {
+		return "", ""
+	}
+	return hub, relLink(feed.Channel.AtomLinks, "self")
+}
+
+func (a *atomFeed) toRSS() *RSSFeed {
+	var feed RSSFeed
+	feed.Base = a.Base
+	feed.Channel.Title = a.Title
+	feed.Channel.Link = alternateLink(a.Links)
+	feed.Channel.AtomLinks = a.Links
+	feed.Channel.Description = a.Subtitle
+	for _, entry := range a.Entries {
+		item := RSSItem{
+			Base:        entry.Base,
+			GUID:        entry.ID,
+			Title:       entry.Title,
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary,
+			Content:     entry.Content,
+			Comments:    relLink(entry.Links, "replies"),
+			PubDate:     entry.Published,
+		}
+		if item.Description == "" {
+			item.Description = entry.Content
+		}
+		var authors []string
+		for _, author := range entry.Authors {
+			authors = append(authors, author.Name)
+		}
+		item.Author = strings.Join(authors, ", ")
+		for _, category := range entry.Categories {
+			item.Categories = append(item.Categories, category.Term)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{
+					URL:    link.Href,
+					Type:   link.Type,
+					Length: link.Length,
+				})
+			}
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return &feed
+}
+
+// parseFeed decodes an RSS 2.0 or Atom document into an RSSFeed. A document
+// the XML decoder rejects is parsed again leniently, and the problems found
+// are recorded in the feed's Warnings.
+func parseFeed(body []byte) (*RSSFeed, error) {
+	feed, err := parseFeedStrict(body)
+	if err == nil {
+		return feed, nil
+	}
+	feed, warnings := parseFeedLenient(body)
+	if feed == nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	feed.Warnings = append([]string{fmt.Sprintf("not well-formed XML: %s", err)}, warnings...)
+	return feed, nil
+}
+
+func parseFeedStrict(body []byte) (*RSSFeed, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := unmarshalXML(body, &root); err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	if root.XMLName.Local == "feed" {
+		var atom atomFeed
+		if err := unmarshalXML(body, &atom); err != nil {
+			return nil, err
+		}
+		return atom.toRSS(), nil
 	}
 
 	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
+	if err := unmarshalXML(body, &feed); err != nil {
 		return nil, err
 	}
+	return &feed, nil
+}
 
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for i := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+// itemKey returns the value used to recognise an item across fetches of the
+// same feed: its guid, else its link, else a hash of its content.
+func itemKey(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
 	}
+	return hashFields(item.Title, item.Link, item.Description)
+}
 
-	return &feed, nil
+// contentHashVersion prefixes content hashes so that posts hashed over an
+// older set of fields, or before links were normalized, are refreshed
+// without being recorded as edits.
+const contentHashVersion = "v4:"
+
+// contentHash fingerprints the parts of an item a publisher may correct
+// after it was first published.
+func contentHash(item RSSItem) string {
+	fields := []string{
+		item.Title, item.Link, item.Description, item.Content, item.author(), item.Comments,
+		item.Duration, item.Episode, item.Season, item.Image.Href,
+	}
+	fields = append(fields, item.Categories...)
+	for _, enclosure := range item.Enclosures {
+		fields = append(fields, enclosure.URL, enclosure.Type, enclosure.Length)
+	}
+	return contentHashVersion + hashFields(fields...)
+}
+
+func hashFields(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return "sha256:" + hex.EncodeToString(sum[:])
 }
 
-func ScrapeFeeds(ctx context.Context, db *database.Queries) error {
+// author prefers dc:creator, which holds a name, over the RSS author
+// element, which is meant to hold an email address.
+func (item RSSItem) author() string {
+	if creator := strings.TrimSpace(item.Creator); creator != "" {
+		return creator
+	}
+	return strings.TrimSpace(item.Author)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt32(s string) sql.NullInt32 {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}
+}
+
+// parseDuration reads an itunes:duration, given either in seconds or as
+// [[HH:]MM:]SS.
+func parseDuration(s string) sql.NullInt32 {
+	var seconds int64
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		n, err := strconv.ParseInt(part, 10, 32)
+		if err != nil || n < 0 {
+			return sql.NullInt32{}
+		}
+		seconds = seconds*60 + n
+	}
+	return sql.NullInt32{Int32: int32(seconds), Valid: true}
+}
+
+// pubDateLayouts are the date formats seen in RSS pubDate and Atom
+// published and updated elements, most common first. RSS asks for RFC 822
+// dates, but many feeds drop the weekday, the leading zero of the day or the
+// seconds.
+var pubDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// publishedAt parses an item's publication date. Items without a date the
+// layouts above understand, and items dated in the future, are taken to have
+// been published at now.
+func publishedAt(pubDate string, now time.Time) time.Time {
+	pubDate = strings.Join(strings.Fields(pubDate), " ")
+	if pubDate == "" {
+		return now
+	}
+	for _, layout := range pubDateLayouts {
+		t, err := time.Parse(layout, pubDate)
+		if err != nil {
+			continue
+		}
+		if t.After(now) {
+			return now
+		}
+		return t.UTC()
+	}
+	return now
+}
+
+func ScrapeFeeds(ctx context.Context, conn *sql.DB, fetcher *Fetcher) error {
+	db := database.New(conn)
 	nextFeed, err := db.GetNextFeedToFetch(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Every feed is gone or waiting for its host's Retry-After.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -78,36 +350,253 @@ func ScrapeFeeds(ctx context.Context, db *database.Queries) error {
 		return err
 	}
 
-	feed, err := FetchFeed(ctx, nextFeed.Url)
+	feed, movedTo, err := fetcher.FetchFeed(ctx, nextFeed.Url)
+	if errors.Is(err, ErrFeedGone) {
+		fmt.Printf("Feed %s is gone, no longer fetching it\n", nextFeed.Url)
+		return db.DeactivateFeed(ctx, nextFeed.ID)
+	}
+	var retry *RetryAfterError
+	if errors.As(err, &retry) {
+		fmt.Printf("Feed %s: %s, not fetching it until then\n", nextFeed.Url, retry)
+		return db.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+			ID:          nextFeed.ID,
+			NextFetchAt: sql.NullTime{Time: retry.Until, Valid: true},
+		})
+	}
+	if errors.Is(err, ErrRobotsDisallowed) {
+		fmt.Printf("Skipping feed %s: %s\n", nextFeed.Url, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	feedID, err := followRedirect(ctx, conn, db, nextFeed, movedTo)
+	if err != nil {
+		return err
+	}
+
+	warnings := []string{}
+	for _, warning := range feed.Warnings {
+		fmt.Printf("Warning parsing %s: %s\n", nextFeed.Url, warning)
+		warnings = append(warnings, warning)
+	}
+	err = db.SetFeedParseWarnings(ctx, database.SetFeedParseWarningsParams{ID: feedID, ParseWarnings: warnings})
+	if err != nil {
+		return err
+	}
+
+	hub, topic := feed.WebSubLinks()
+	if hub != "" && topic == "" {
+		topic = nextFeed.Url
+	}
+	err = db.SetFeedHub(ctx, database.SetFeedHubParams{
+		ID:       feedID,
+		HubUrl:   nullString(hub),
+		TopicUrl: nullString(topic),
+	})
 	if err != nil {
 		return err
 	}
 
+	return SavePosts(ctx, db, feedID, feed)
+}
+
+// PostStore is the part of *database.Queries that SavePosts uses.
+type PostStore interface {
+	GetPostByItemKey(ctx context.Context, arg database.GetPostByItemKeyParams) (database.Post, error)
+	AdoptPost(ctx context.Context, arg database.AdoptPostParams) (database.Post, error)
+	UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.Post, error)
+	CreatePostEdit(ctx context.Context, arg database.CreatePostEditParams) error
+	UpsertPostEnclosure(ctx context.Context, arg database.UpsertPostEnclosureParams) (database.PostEnclosure, error)
+	DeleteStalePostEnclosures(ctx context.Context, arg database.DeleteStalePostEnclosuresParams) error
+	MarkFilteredPostRead(ctx context.Context, id uuid.UUID) error
+}
+
+// SavePosts stores the items of feed as posts of feedID.
+func SavePosts(ctx context.Context, db PostStore, feedID uuid.UUID, feed *RSSFeed) error {
 	for _, item := range feed.Channel.Item {
-		_, err := db.CreatePost(ctx, database.CreatePostParams{
+		if err := savePost(ctx, db, feedID, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePost inserts item as a post of feedID, or updates the stored post when
+// the item's content has changed since it was last seen. The previous title
+// and description of an updated post are kept in post_edits.
+func savePost(ctx context.Context, db PostStore, feedID uuid.UUID, item RSSItem) error {
+	key := itemKey(item)
+	hash := contentHash(item)
+
+	existing, err := db.GetPostByItemKey(ctx, database.GetPostByItemKeyParams{
+		FeedID:  feedID,
+		ItemKey: key,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err = adoptLegacyPost(ctx, db, feedID, key, item)
+	}
+	found := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if found && existing.ContentHash == hash {
+		return nil
+	}
+
+	summarySource := item.Description
+	if strings.TrimSpace(summarySource) == "" {
+		summarySource = item.Content
+	}
+	categories := []string{}
+	for _, category := range item.Categories {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+
+	now := time.Now()
+	post, err := db.UpsertPost(ctx, database.UpsertPostParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title:     item.Title,
+		Url:       item.Link,
+		Description: sql.NullString{
+			String: SanitizeHTML(item.Description),
+			Valid:  true,
+		},
+		PublishedAt: sql.NullTime{
+			Time:  publishedAt(item.PubDate, now),
+			Valid: true,
+		},
+		FeedID:      feedID,
+		ItemKey:     key,
+		ContentHash: hash,
+		Summary: sql.NullString{
+			String: Summary(summarySource),
+			Valid:  true,
+		},
+		Content:         nullString(SanitizeHTML(item.Content)),
+		Author:          nullString(item.author()),
+		Categories:      categories,
+		CommentsUrl:     nullString(strings.TrimSpace(item.Comments)),
+		DurationSeconds: parseDuration(item.Duration),
+		Episode:         nullInt32(item.Episode),
+		Season:          nullInt32(item.Season),
+		ImageUrl:        nullString(strings.TrimSpace(item.Image.Href)),
+	})
+	if errors.Is(err, sql.ErrNoRows) || database.IsUniqueViolation(err) {
+		// Stored concurrently with the same content.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := saveEnclosures(ctx, db, post.ID, item.Enclosures); err != nil {
+		return err
+	}
+
+	if !found {
+		// Followers with a matching mark-read filter never see the post as
+		// unread.
+		if err := db.MarkFilteredPostRead(ctx, post.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Added post: %s\n", post.Title)
+		return nil
+	}
+
+	// Posts hashed before the current set of fields was tracked are being
+	// backfilled, not edited.
+	if !strings.HasPrefix(existing.ContentHash, contentHashVersion) {
+		return nil
+	}
+	err = db.CreatePostEdit(ctx, database.CreatePostEditParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		PostID:      existing.ID,
+		Title:       existing.Title,
+		Description: existing.Description,
+		ContentHash: existing.ContentHash,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Updated post: %s\n", post.Title)
+	return nil
+}
+
+// legacyItemKeys returns the keys item may have been stored under by earlier
+// versions of gator. Posts stored before items were keyed were given their
+// link as key, whether or not the item has a guid, and items without a guid
+// were keyed by their link before links were normalized.
+func legacyItemKeys(item RSSItem, key string) []string {
+	var keys []string
+	candidates := []string{item.Link, strings.TrimSpace(item.Link), item.RawLink, strings.TrimSpace(item.RawLink)}
+	for _, candidate := range candidates {
+		if candidate != "" && candidate != key && !slices.Contains(keys, candidate) {
+			keys = append(keys, candidate)
+		}
+	}
+	return keys
+}
+
+// adoptLegacyPost finds a post of feedID stored under one of the legacy keys
+// of item and gives it key, so that upgrading does not store the item twice
+// and its read and star state is kept. It returns sql.ErrNoRows when there
+// is no such post.
+func adoptLegacyPost(ctx context.Context, db PostStore, feedID uuid.UUID, key string, item RSSItem) (database.Post, error) {
+	legacyKeys := legacyItemKeys(item, key)
+	if len(legacyKeys) == 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	post, err := db.AdoptPost(ctx, database.AdoptPostParams{
+		ItemKey:    key,
+		FeedID:     feedID,
+		LegacyKeys: legacyKeys,
+	})
+	if database.IsUniqueViolation(err) {
+		// Stored under key concurrently.
+		return database.Post{}, sql.ErrNoRows
+	}
+	return post, err
+}
+
+// saveEnclosures stores the enclosures of a post, keeping any existing row
+// for an unchanged URL and removing enclosures the item no longer lists.
+func saveEnclosures(ctx context.Context, db PostStore, postID uuid.UUID, enclosures []RSSEnclosure) error {
+	urls := []string{}
+	for _, enclosure := range enclosures {
+		enclosureURL := strings.TrimSpace(enclosure.URL)
+		if enclosureURL == "" {
+			continue
+		}
+
+		var length sql.NullInt64
+		if n, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && n > 0 {
+			length = sql.NullInt64{Int64: n, Valid: true}
+		}
+
+		_, err := db.UpsertPostEnclosure(ctx, database.UpsertPostEnclosureParams{
 			ID:        uuid.New(),
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-			Title:     item.Title,
-			Url:       item.Link,
-			Description: sql.NullString{
-				String: item.Description,
-				Valid:  true,
-			},
-			PublishedAt: sql.NullTime{
-				Time:  time.Now(),
-				Valid: true,
-			},
-			FeedID: nextFeed.ID,
+			PostID:    postID,
+			Url:       enclosureURL,
+			MimeType:  nullString(strings.TrimSpace(enclosure.Type)),
+			Length:    length,
 		})
 		if err != nil {
-			if err.Error() == "pq: duplicate key value violates unique constraint \"posts_url_key\"" {
-				continue
-			}
 			return err
 		}
-		fmt.Printf("Added post: %s\n", item.Title)
+		urls = append(urls, enclosureURL)
 	}
 
-	return nil
+	return db.DeleteStalePostEnclosures(ctx, database.DeleteStalePostEnclosuresParams{
+		PostID: postID,
+		Urls:   urls,
+	})
 }
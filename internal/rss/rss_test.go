@@ -0,0 +1,55 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishedAt(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		pubDate string
+		want    time.Time
+	}{
+		{"Mon, 05 Oct 2026 09:30:00 +0000", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"Mon, 05 Oct 2026 09:30:00 GMT", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"Mon, 5 Oct 2026 11:30:00 +0200", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"Mon, 5 Oct 2026 09:30 +0000", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"5 Oct 2026 09:30:00 +0000", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"  Mon, 05 Oct 2026\n\t09:30:00 +0000 ", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"2026-10-05T09:30:00Z", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"2026-10-05T11:30:00+02:00", time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)},
+		{"2026-10-05", time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{"2019-01-01T00:00:00Z", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Missing, unparseable and future dates fall back to now.
+		{"", now},
+		{"yesterday", now},
+		{"2030-01-01T00:00:00Z", now},
+	}
+	for _, tt := range tests {
+		if got := publishedAt(tt.pubDate, now); !got.Equal(tt.want) {
+			t.Errorf("publishedAt(%q) = %s, want %s", tt.pubDate, got, tt.want)
+		}
+	}
+}
+
+func TestAtomPublishedDate(t *testing.T) {
+	feed, err := parseFeed([]byte(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Blog</title>
+<entry><id>1</id><title>Both</title><published>2026-01-02T00:00:00Z</published><updated>2026-03-04T00:00:00Z</updated></entry>
+<entry><id>2</id><title>Updated only</title><updated>2026-03-04T00:00:00Z</updated></entry>
+</feed>`))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	want := []string{"2026-01-02T00:00:00Z", "2026-03-04T00:00:00Z"}
+	if len(feed.Channel.Item) != len(want) {
+		t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(want))
+	}
+	for i, item := range feed.Channel.Item {
+		if item.PubDate != want[i] {
+			t.Errorf("item %d PubDate = %q, want %q", i, item.PubDate, want[i])
+		}
+	}
+}
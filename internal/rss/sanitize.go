@@ -0,0 +1,321 @@
+package rss
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxSummaryLength is the number of characters kept in a post's plaintext
+// summary.
+const maxSummaryLength = 500
+
+// allowedTags maps the elements kept by SanitizeHTML to the attributes they
+// may keep.
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"abbr":       {"title"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"dd":         nil,
+	"del":        nil,
+	"div":        nil,
+	"dl":         nil,
+	"dt":         nil,
+	"em":         nil,
+	"figcaption": nil,
+	"figure":     nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "title", "width", "height"},
+	"ins":        nil,
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"q":          nil,
+	"s":          nil,
+	"small":      nil,
+	"span":       nil,
+	"strong":     nil,
+	"sub":        nil,
+	"sup":        nil,
+	"table":      nil,
+	"tbody":      nil,
+	"td":         nil,
+	"tfoot":      nil,
+	"th":         nil,
+	"thead":      nil,
+	"tr":         nil,
+	"u":          nil,
+	"ul":         nil,
+}
+
+// droppedTags are removed together with everything inside them.
+var droppedTags = map[string]bool{
+	"button":   true,
+	"embed":    true,
+	"form":     true,
+	"frame":    true,
+	"frameset": true,
+	"head":     true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"select":   true,
+	"style":    true,
+	"svg":      true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+}
+
+// voidTags have no end tag, so they neither stay open nor start a dropped
+// subtree.
+var voidTags = map[string]bool{
+	"br":    true,
+	"embed": true,
+	"frame": true,
+	"hr":    true,
+	"img":   true,
+}
+
+// blockTags separate words when an HTML fragment is flattened to text.
+var blockTags = map[string]bool{
+	"blockquote": true,
+	"br":         true,
+	"dd":         true,
+	"div":        true,
+	"dt":         true,
+	"figcaption": true,
+	"h1":         true,
+	"h2":         true,
+	"h3":         true,
+	"h4":         true,
+	"h5":         true,
+	"h6":         true,
+	"hr":         true,
+	"li":         true,
+	"p":          true,
+	"pre":        true,
+	"td":         true,
+	"th":         true,
+	"tr":         true,
+}
+
+// SanitizeHTML returns fragment reduced to a small set of presentational
+// elements. Scripts, styles, embedded frames, event handler attributes,
+// non-http(s) links and tracking pixels are removed, and unclosed elements are
+// closed.
+func SanitizeHTML(fragment string) string {
+	var b strings.Builder
+	var open []string
+	dropDepth := 0
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := z.Token()
+		name := token.Data
+
+		if dropDepth > 0 {
+			if droppedTags[name] && !voidTags[name] {
+				switch tt {
+				case html.StartTagToken:
+					dropDepth++
+				case html.EndTagToken:
+					dropDepth--
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			b.WriteString(html.EscapeString(token.Data))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[name] {
+				if tt == html.StartTagToken && !voidTags[name] {
+					dropDepth++
+				}
+				continue
+			}
+			attrs, ok := allowedAttrs(token)
+			if !ok {
+				continue
+			}
+			writeStartTag(&b, name, attrs)
+			if !voidTags[name] {
+				open = append(open, name)
+			}
+		case html.EndTagToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// allowedAttrs filters the attributes of an element kept by SanitizeHTML. It
+// reports false if the element should be dropped altogether.
+func allowedAttrs(token html.Token) ([]html.Attribute, bool) {
+	allowed, ok := allowedTags[token.Data]
+	if !ok {
+		return nil, false
+	}
+
+	var attrs []html.Attribute
+	for _, attr := range token.Attr {
+		if attr.Namespace != "" || !contains(allowed, attr.Key) {
+			continue
+		}
+		if attr.Key == "href" || attr.Key == "src" {
+			if !isSafeURL(attr.Val) {
+				continue
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+
+	switch token.Data {
+	case "a":
+		attrs = append(attrs, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+	case "img":
+		if !hasAttr(attrs, "src") || isTrackingPixel(attrs) {
+			return nil, false
+		}
+	}
+	return attrs, true
+}
+
+func writeStartTag(b *strings.Builder, name string, attrs []html.Attribute) {
+	b.WriteString("<" + name)
+	for _, attr := range attrs {
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	b.WriteString(">")
+}
+
+func isSafeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// isTrackingPixel reports whether an image is sized to be invisible, the
+// usual shape of the analytics beacons publishers put in feed items.
+func isTrackingPixel(attrs []html.Attribute) bool {
+	for _, attr := range attrs {
+		if attr.Key != "width" && attr.Key != "height" {
+			continue
+		}
+		switch strings.TrimSuffix(strings.TrimSpace(attr.Val), "px") {
+		case "0", "1":
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttr(attrs []html.Attribute, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// PlainText returns the visible text of an HTML fragment with whitespace
+// collapsed into single spaces.
+func PlainText(fragment string) string {
+	var b strings.Builder
+	dropDepth := 0
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := z.Token()
+
+		switch tt {
+		case html.TextToken:
+			if dropDepth == 0 {
+				b.WriteString(token.Data)
+			}
+			continue
+		case html.StartTagToken:
+			if droppedTags[token.Data] && !voidTags[token.Data] {
+				dropDepth++
+			}
+		case html.EndTagToken:
+			if droppedTags[token.Data] && dropDepth > 0 {
+				dropDepth--
+			}
+		}
+		// Keep the words of adjacent blocks such as "<p>one</p><p>two</p>"
+		// apart.
+		if blockTags[token.Data] {
+			b.WriteString(" ")
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Summary returns a plaintext excerpt of an HTML fragment, cut at a word
+// boundary when it is longer than maxSummaryLength.
+func Summary(fragment string) string {
+	text := PlainText(fragment)
+	if len([]rune(text)) <= maxSummaryLength {
+		return text
+	}
+
+	runes := []rune(text)[:maxSummaryLength]
+	if i := strings.LastIndexByte(string(runes), ' '); i > 0 {
+		return string(runes)[:i] + "…"
+	}
+	return string(runes) + "…"
+}
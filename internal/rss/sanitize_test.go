@@ -0,0 +1,77 @@
+package rss
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"plain", "<p>Hello <b>world</b></p>", "<p>Hello <b>world</b></p>"},
+		{"escaped text", "<p>1 &lt; 2 &amp; 3</p>", "<p>1 &lt; 2 &amp; 3</p>"},
+
+		// Scripts and styles are dropped with their contents.
+		{"script", "<p>a</p><script>alert(1)</script><p>b</p>", "<p>a</p><p>b</p>"},
+		{"script with markup", "<script>document.write('<p>x</p>')</script>ok", "ok"},
+		{"uppercase script", "<SCRIPT>alert(1)</SCRIPT>ok", "ok"},
+		{"unclosed script", "ok<script>alert(1)", "ok"},
+		{"style", "<style>p { color: red }</style><p>a</p>", "<p>a</p>"},
+		{"noscript", "<noscript><img src=\"https://example.com/a.png\"></noscript>ok", "ok"},
+
+		// Event handlers and other unknown attributes are dropped.
+		{"onclick", `<p onclick="alert(1)">a</p>`, "<p>a</p>"},
+		{"onerror", `<img src="https://example.com/a.png" onerror="alert(1)">`, `<img src="https://example.com/a.png">`},
+		{"onmouseover", `<a href="https://example.com/" onmouseover="alert(1)">a</a>`, `<a href="https://example.com/" rel="nofollow noopener noreferrer">a</a>`},
+		{"style attribute", `<span style="display:none">a</span>`, "<span>a</span>"},
+
+		// Only http(s), mailto and relative URLs are kept.
+		{"javascript href", `<a href="javascript:alert(1)">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"javascript href mixed case", `<a href=" JaVaScRiPt:alert(1)">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"javascript href entities", `<a href="javascript&#58;alert(1)">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"vbscript href", `<a href="vbscript:msgbox(1)">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD4=">a</a>`, `<a rel="nofollow noopener noreferrer">a</a>`},
+		{"data src", `<img src="data:image/svg+xml,<svg onload=alert(1)>">`, ""},
+		{"javascript src", `<img src="javascript:alert(1)" alt="x">`, ""},
+		{"relative href", `<a href="/posts/1">a</a>`, `<a href="/posts/1" rel="nofollow noopener noreferrer">a</a>`},
+		{"mailto href", `<a href="mailto:me@example.com">a</a>`, `<a href="mailto:me@example.com" rel="nofollow noopener noreferrer">a</a>`},
+		{"quoted url", `<a href="https://example.com/?a=1&amp;b=&quot;2&quot;">a</a>`, `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;" rel="nofollow noopener noreferrer">a</a>`},
+
+		// Frames and embedded objects are dropped with their contents.
+		{"iframe", `<p>a</p><iframe src="https://example.com/embed"><p>fallback</p></iframe><p>b</p>`, "<p>a</p><p>b</p>"},
+		{"self-closing iframe", `<iframe src="https://example.com/embed"/>ok`, "ok"},
+		// As in a browser, an iframe's contents are raw text and its first
+		// end tag closes it, so what follows is sanitized as ordinary markup.
+		{"nested iframes", `<iframe><iframe></iframe><b>inner</b></iframe>ok`, "<b>inner</b>ok"},
+		{"embed", `<embed src="https://example.com/a.swf"><p>a</p>`, "<p>a</p>"},
+		{"object", `<object data="https://example.com/a.swf"><embed src="https://example.com/a.swf"></object>ok`, "ok"},
+		{"form", `<form action="https://example.com/"><input name="q"><button>Go</button></form>ok`, "ok"},
+
+		// Invisible images are tracking pixels.
+		{"tracking pixel", `<p>a<img src="https://example.com/pixel.gif" width="1" height="1"></p>`, "<p>a</p>"},
+		{"zero size pixel", `<img src="https://example.com/pixel.gif" width="0" height="0">`, ""},
+		{"pixel size in px", `<img src="https://example.com/pixel.gif" width="1px">`, ""},
+		{"pixel height only", `<img src="https://example.com/pixel.gif" height="1">`, ""},
+		{"one pixel high", `<img src="https://example.com/a.png" width="100" height="1" alt="chart">`, ""},
+		{"sized image", `<img src="https://example.com/a.png" width="640" height="480" alt="chart">`, `<img src="https://example.com/a.png" width="640" height="480" alt="chart">`},
+		{"image without src", `<img alt="x">`, ""},
+
+		// Malformed nesting is repaired so the fragment cannot leak markup
+		// into the page it is shown in.
+		{"unclosed", "<p><b>a", "<p><b>a</b></p>"},
+		{"misnested", "<b><i>a</b>b</i>", "<b><i>a</i></b>b"},
+		{"stray end tag", "a</div></p>b", "ab"},
+		{"unknown tags kept as text", "<blink><p>a</p></blink>", "<p>a</p>"},
+		{"unclosed list", "<ul><li>a<li>b", "<ul><li>a<li>b</li></li></ul>"},
+		{"close outer element", "<div><p><em>a</div>b", "<div><p><em>a</em></p></div>b"},
+		{"attribute without quotes", `<a href=https://example.com/ onclick=alert(1)>a</a>`, `<a href="https://example.com/" rel="nofollow noopener noreferrer">a</a>`},
+		{"unterminated tag", `<p>a<img src="https://example.com/a.png`, "<p>a</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.fragment); got != tt.want {
+				t.Errorf("SanitizeHTML(%q)\n got %q\nwant %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Subscription is a request to a hub to deliver the updates of Topic to
+// Callback.
+type Subscription struct {
+	Hub      string
+	Topic    string
+	Callback string
+	// Secret is used by the hub to sign the content it delivers.
+	Secret string
+	// LeaseSeconds is the lease asked for; the hub may grant another.
+	LeaseSeconds int
+}
+
+// Subscribe sends a WebSub (https://www.w3.org/TR/websub/) subscription
+// request to the hub. The hub accepts it by verifying the intent with a GET
+// request to the callback, which may happen before or after Subscribe
+// returns.
+func Subscribe(ctx context.Context, client *http.Client, sub Subscription) error {
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {sub.Callback},
+		"hub.secret":   {sub.Secret},
+	}
+	if sub.LeaseSeconds > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(sub.LeaseSeconds))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub answered %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// VerifySignature reports whether header, the X-Hub-Signature of a content
+// delivery in the form method=hex, is the HMAC of body keyed with secret.
+func VerifySignature(header, secret string, body []byte) bool {
+	method, signature, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok {
+		return false
+	}
+	newHash, ok := signatureHashes[strings.ToLower(method)]
+	if !ok {
+		return false
+	}
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
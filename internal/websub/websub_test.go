@@ -0,0 +1,115 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	var got url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		r.ParseForm()
+		got = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	err := Subscribe(context.Background(), hub.Client(), Subscription{
+		Hub:          hub.URL,
+		Topic:        "https://example.com/feed",
+		Callback:     "https://gator.example.com/websub/1",
+		Secret:       "s3cret",
+		LeaseSeconds: 3600,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	want := map[string]string{
+		"hub.mode":          "subscribe",
+		"hub.topic":         "https://example.com/feed",
+		"hub.callback":      "https://gator.example.com/websub/1",
+		"hub.secret":        "s3cret",
+		"hub.lease_seconds": "3600",
+	}
+	for key, value := range want {
+		if got.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, got.Get(key), value)
+		}
+	}
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown topic", http.StatusBadRequest)
+	}))
+	defer hub.Close()
+
+	err := Subscribe(context.Background(), hub.Client(), Subscription{Hub: hub.URL, Topic: "https://example.com/feed"})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "unknown topic") {
+		t.Fatalf("Subscribe = %v, want the hub's 400 answer", err)
+	}
+}
+
+func TestSubscribeCanceled(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Subscribe(ctx, hub.Client(), Subscription{Hub: hub.URL}); err == nil {
+		t.Fatal("Subscribe with a canceled context succeeded")
+	}
+}
+
+func sign(newHash func() hash.Hash, secret, body string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "s3cret"
+	const body = "<feed></feed>"
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"sha1", "sha1=" + sign(sha1.New, secret, body), true},
+		{"sha256", "sha256=" + sign(sha256.New, secret, body), true},
+		{"uppercase method", "SHA256=" + sign(sha256.New, secret, body), true},
+		{"wrong secret", "sha256=" + sign(sha256.New, "other", body), false},
+		{"other body", "sha256=" + sign(sha256.New, secret, "<feed/>"), false},
+		{"method mismatch", "sha1=" + sign(sha256.New, secret, body), false},
+		{"unknown method", "md5=" + sign(sha256.New, secret, body), false},
+		{"not hex", "sha256=zz", false},
+		{"no method", sign(sha256.New, secret, body), false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.header, secret, []byte(body)); got != tt.want {
+				t.Errorf("VerifySignature(%q) = %t, want %t", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,8 +19,9 @@ import (
 )
 
 type state struct {
-	cfg *config.ConfigFile
-	db  *database.Queries
+	cfg  *config.ConfigFile
+	conn *sql.DB
+	db   *database.Queries
 }
 
 type command struct {
@@ -32,17 +35,25 @@ type commands struct {
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+		user, err := sessionUser(context.Background(), s.db, s.cfg.SessionToken)
 		if err != nil {
-			return fmt.Errorf("failed to get user: %w", err)
-		}
-		if user.Name == "" {
-			return fmt.Errorf("user not found")
+			return fmt.Errorf("%w; run gator login <name>", err)
 		}
 		return handler(s, cmd, user)
 	}
 }
 
+// middlewareAdmin is middlewareLoggedIn for commands only administrators may
+// run.
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		if user.Role != roleAdmin {
+			return fmt.Errorf("%s requires an admin user", cmd.command)
+		}
+		return handler(s, cmd, user)
+	})
+}
+
 func (c *commands) run(s *state, cmd command) error {
 	handler, ok := c.allCommands[cmd.command]
 	if !ok {
@@ -61,14 +72,22 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("follow requires a feed URL")
 	}
 
-	feedURL := cmd.args[0]
+	storedFeedFollow, err := followFeed(context.Background(), s.db, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feed: %s Followed by: %s\n", storedFeedFollow.FeedName, storedFeedFollow.UserName)
+	return nil
+}
 
-	feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+func followFeed(ctx context.Context, db apiStore, user database.User, feedURL string) (database.CreateFeedFollowRow, error) {
+	feed, err := db.GetFeedByURL(ctx, feedURL)
 	if err != nil {
-		return fmt.Errorf("failed to get feed: %w", err)
+		return database.CreateFeedFollowRow{}, fmt.Errorf("failed to get feed: %w", err)
 	}
 
-	storedFeedFollow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	storedFeedFollow, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -76,11 +95,9 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		UserID:    user.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create feed follow: %w", err)
+		return database.CreateFeedFollowRow{}, fmt.Errorf("failed to create feed follow: %w", err)
 	}
-
-	fmt.Printf("Feed: %s Followed by: %s\n", storedFeedFollow.FeedName, storedFeedFollow.UserName)
-	return nil
+	return storedFeedFollow, nil
 }
 
 func handlerFeeds(s *state, cmd command) error {
@@ -94,11 +111,22 @@ func handlerFeeds(s *state, cmd command) error {
 	}
 
 	for _, feed := range feeds {
-		user, err := s.db.GetUserByID(context.Background(), feed.UserID)
-		if err != nil {
-			return fmt.Errorf("failed to get user: %w", err)
+		addedBy := "(deleted user)"
+		if feed.UserID.Valid {
+			user, err := s.db.GetUserByID(context.Background(), feed.UserID.UUID)
+			if err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			addedBy = user.Name
+		}
+		if feed.DeactivatedAt.Valid {
+			fmt.Printf("* %s - %s Added by: %s (gone since %s)\n", feed.Name, feed.Url, addedBy, feed.DeactivatedAt.Time.Format(time.DateOnly))
+		} else {
+			fmt.Printf("* %s - %s Added by: %s\n", feed.Name, feed.Url, addedBy)
+		}
+		for _, warning := range feed.ParseWarnings {
+			fmt.Printf("    warning: %s\n", warning)
 		}
-		fmt.Printf("* %s - %s Added by: %s\n", feed.Name, feed.Url, user.Name)
 	}
 	return nil
 }
@@ -108,21 +136,31 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("add-feed requires a name and URL")
 	}
 
-	storedFeed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+	storedFeed, storedFeedFollow, err := addFeed(context.Background(), s.db, user, cmd.args[0], cmd.args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feed created: %+v\n", storedFeed)
+	fmt.Printf("Feed: %s Followed by: %s\n", storedFeedFollow.FeedName, storedFeedFollow.UserName)
+	return nil
+}
+
+// addFeed creates a feed and makes user its first follower.
+func addFeed(ctx context.Context, db apiStore, user database.User, name, feedURL string) (database.Feed, database.CreateFeedFollowRow, error) {
+	storedFeed, err := db.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		Name:      cmd.args[0],
-		Url:       cmd.args[1],
-		UserID:    user.ID,
+		Name:      name,
+		Url:       feedURL,
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create feed: %w", err)
+		return database.Feed{}, database.CreateFeedFollowRow{}, fmt.Errorf("failed to create feed: %w", err)
 	}
 
-	fmt.Printf("Feed created: %+v\n", storedFeed)
-
-	storedFeedFollow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	storedFeedFollow, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -130,11 +168,53 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		UserID:    user.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create feed follow: %w", err)
+		return database.Feed{}, database.CreateFeedFollowRow{}, fmt.Errorf("failed to create feed follow: %w", err)
 	}
+	return storedFeed, storedFeedFollow, nil
+}
 
-	fmt.Printf("Feed: %s Followed by: %s\n", storedFeedFollow.FeedName, storedFeedFollow.UserName)
-	return nil
+// retryAfter returns how long to put off a task that just failed, when its
+// previous failures attempts had failed too: delay, doubled for each earlier
+// failure, up to maxDelay.
+func retryAfter(failures int32, delay, maxDelay time.Duration) time.Duration {
+	for range failures {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+func fetcherFromConfig(cfg config.FetchConfig) (*rss.Fetcher, error) {
+	opts := rss.FetcherOptions{
+		MaxBodyBytes:    cfg.MaxBodyBytes,
+		UserAgent:       cfg.UserAgent,
+		Proxy:           cfg.Proxy,
+		CABundle:        cfg.CABundle,
+		MaxConnsPerHost: cfg.MaxConnsPerHost,
+		RobotsTxt:       cfg.RobotsTxt,
+		TrackingParams:  cfg.TrackingParams,
+	}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fetch timeout: %w", err)
+		}
+		opts.Timeout = timeout
+	}
+	if cfg.HostDelay != "" {
+		hostDelay, err := time.ParseDuration(cfg.HostDelay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fetch host delay: %w", err)
+		}
+		opts.HostDelay = hostDelay
+	}
+	fetcher, err := rss.NewFetcher(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fetcher: %w", err)
+	}
+	return fetcher, nil
 }
 
 func handlerAgg(s *state, cmd command) error {
@@ -146,46 +226,131 @@ func handlerAgg(s *state, cmd command) error {
 		return fmt.Errorf("failed to parse time between requests: %w", err)
 	}
 
+	if s.cfg.Prune.Interval != "" {
+		pruneInterval, err := time.ParseDuration(s.cfg.Prune.Interval)
+		if err != nil {
+			return fmt.Errorf("failed to parse prune interval: %w", err)
+		}
+		pruneOpts, err := pruneOptionsFromConfig(s.cfg.Prune)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Pruning posts every %s\n", pruneInterval)
+		pruneTicker := time.NewTicker(pruneInterval)
+		defer pruneTicker.Stop()
+		go func() {
+			for range pruneTicker.C {
+				if err := prunePosts(context.Background(), s.db, pruneOpts); err != nil {
+					fmt.Printf("error pruning posts: %s\n", err)
+				}
+			}
+		}()
+	}
+
+	fetcher, err := fetcherFromConfig(s.cfg.Fetch)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Collecting feeds every %s\n", timeBetweenReqs)
 	ticker := time.NewTicker(timeBetweenReqs)
 	for ; ; <-ticker.C {
-		err := rss.ScrapeFeeds(context.Background(), s.db)
+		err := rss.ScrapeFeeds(context.Background(), s.conn, fetcher)
 		if err != nil {
 			fmt.Printf("error scraping feeds: %s\n", err)
 			return err
 		}
+
+		if err := downloadPendingEpisodes(context.Background(), s, fetcher); err != nil {
+			fmt.Printf("error downloading episodes: %s\n", err)
+		}
+
+		if s.cfg.SMTP.Host != "" {
+			if err := sendDueDigests(context.Background(), s); err != nil {
+				fmt.Printf("error sending digests: %s\n", err)
+			}
+		}
 	}
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd)
+	long := fs.Bool("long", false, "print a plaintext excerpt of each post")
+	unread := fs.Bool("unread", false, "only show posts you have not read")
+	unfiltered := fs.Bool("unfiltered", false, "also show posts hidden by your filters")
+	tag := fs.String("tag", "", "only show posts from feeds with this tag")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("browse: %w", err)
+	}
+
 	limit := 2
-	var err error
-	if len(cmd.args) > 1 {
-		return fmt.Errorf("browse does not take more than 2 arguments")
+	if len(args) > 1 {
+		return fmt.Errorf("browse takes at most one limit argument")
 	}
 
-	if len(cmd.args) == 1 {
-		limit, err = strconv.Atoi(cmd.args[0])
+	if len(args) == 1 {
+		limit, err = strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("failed to parse limit: %w", err)
 		}
 	}
 
 	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
+		UserID:       user.ID,
+		UnreadOnly:   *unread,
+		Tag:          sql.NullString{String: *tag, Valid: *tag != ""},
+		ApplyFilters: !*unfiltered,
+		ByPriority:   true,
+		Limit:        int32(limit),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get posts: %w", err)
 	}
 
 	for _, post := range posts {
-		fmt.Printf("* %s - %s\n", post.Title, post.Url)
+		if post.Updated {
+			fmt.Printf("* %s - %s (updated)\n", post.Title, post.Url)
+		} else {
+			fmt.Printf("* %s - %s\n", post.Title, post.Url)
+		}
+		fmt.Printf("  feed: %s\n", post.FeedName)
+		fmt.Printf("  id: %s\n", post.ID)
+		if *long && post.Summary.String != "" {
+			fmt.Println()
+			for _, line := range wrapText(post.Summary.String, 76) {
+				fmt.Printf("    %s\n", line)
+			}
+			fmt.Println()
+		}
 	}
 
 	return nil
 }
 
+// wrapText splits text into lines of at most width characters, breaking
+// between words. Words longer than width get a line of their own.
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 func handlerUsers(s *state, cmd command) error {
 	if len(cmd.args) != 0 {
 		return fmt.Errorf("list does not take any arguments")
@@ -198,45 +363,59 @@ func handlerUsers(s *state, cmd command) error {
 
 	currentUser := s.cfg.CurrentUserName
 	for _, user := range users {
+		line := "* " + user.Name
+		if user.Role == roleAdmin {
+			line += " (admin)"
+		}
 		if user.Name == currentUser {
-			fmt.Printf("* %s (current)\n", user.Name)
-		} else {
-			fmt.Printf("* %s\n", user.Name)
+			line += " (current)"
 		}
+		fmt.Println(line)
 	}
 
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	if len(cmd.args) != 0 {
-		return fmt.Errorf("reset does not take any arguments")
-	}
-
-	if err := s.db.ResetUsers(context.Background()); err != nil {
-		return fmt.Errorf("failed to reset users: %w", err)
-	}
-
-	fmt.Println("Users reset")
-	return nil
-}
-
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
+	fs := newFlagSet(cmd)
+	reset := fs.Bool("reset", false, "choose a new password using a reset token")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if len(args) != 1 {
 		return fmt.Errorf("login requires a username")
 	}
 
-	userName := cmd.args[0]
-	user, err := s.db.GetUser(context.Background(), userName)
+	ctx := context.Background()
+	userName := args[0]
+	user, err := s.db.GetUser(ctx, userName)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	if user.Name == "" {
-		return fmt.Errorf("user not found")
+	if user.PasswordHash.Valid && !*reset {
+		password, err := readPassword("Password: ")
+		if err != nil {
+			return err
+		}
+		if err := authenticate(user, password); err != nil {
+			return err
+		}
+	} else {
+		// Users without a password, such as those created before passwords
+		// existed, stay locked until an admin gives them a reset token.
+		if !user.PasswordHash.Valid {
+			fmt.Printf("%s has no password yet; enter the reset token an admin gave you.\n", userName)
+		}
+		if err := resetPassword(ctx, s.db, user); err != nil {
+			return err
+		}
 	}
 
-	s.cfg.SetUser(userName)
+	if err := loginLocal(ctx, s, user); err != nil {
+		return err
+	}
 	fmt.Printf("Logged in as %s\n", userName)
 	return nil
 }
@@ -245,21 +424,27 @@ func handlerRegister(s *state, cmd command) error {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("register requires a username")
 	}
-	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.args[0],
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	user, err := s.db.CreateUser(ctx, database.CreateUserParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         cmd.args[0],
+		PasswordHash: sql.NullString{String: hash, Valid: true},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
-	err = s.cfg.SetUser(user.Name)
-	if err != nil {
-		return fmt.Errorf("failed to set user: %w", err)
+	if err := loginLocal(ctx, s, user); err != nil {
+		return err
 	}
 
-	fmt.Printf("User created: %s\nUser Info: %+v\n", user.Name, user)
+	fmt.Printf("User created: %s\nUser ID: %s\n", user.Name, user.ID)
 	return nil
 }
 
@@ -273,35 +458,84 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("failed to get feed follows: %w", err)
 	}
 
+	// Follows are grouped under each of their tags, with untagged follows
+	// listed last.
+	var tags []string
+	byTag := map[string][]database.GetFeedFollowsForUserRow{}
+	var untagged []database.GetFeedFollowsForUserRow
 	for _, follow := range follows {
-		fmt.Printf("* %s - %s\n", follow.FeedName, follow.UserName)
+		if len(follow.Tags) == 0 {
+			untagged = append(untagged, follow)
+		}
+		for _, tag := range follow.Tags {
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], follow)
+		}
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		fmt.Printf("%s:\n", tag)
+		for _, follow := range byTag[tag] {
+			fmt.Printf("  * %s\n", followSummary(follow))
+		}
+	}
+	if len(tags) > 0 && len(untagged) > 0 {
+		fmt.Println("untagged:")
+	}
+	for _, follow := range untagged {
+		if len(tags) > 0 {
+			fmt.Print("  ")
+		}
+		fmt.Printf("* %s\n", followSummary(follow))
 	}
 
 	return nil
 }
 
+// followSummary describes a follow as listed by following, with the name and
+// settings the user chose for it.
+func followSummary(follow database.GetFeedFollowsForUserRow) string {
+	summary := fmt.Sprintf("%s - %s", follow.FeedName, follow.UserName)
+	if follow.Priority != 0 {
+		summary += fmt.Sprintf(" (priority %d)", follow.Priority)
+	}
+	if follow.Muted {
+		summary += " (muted)"
+	}
+	return summary
+}
+
 func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("unfollow requires a feed URL")
 	}
 
-	feedURL := cmd.args[0]
+	feed, err := unfollowFeed(context.Background(), s.db, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
 
-	feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+	fmt.Printf("Feed: %s Unfollowed by: %s\n", feed.Name, user.Name)
+	return nil
+}
+
+func unfollowFeed(ctx context.Context, db apiStore, user database.User, feedURL string) (database.Feed, error) {
+	feed, err := db.GetFeedByURL(ctx, feedURL)
 	if err != nil {
-		return fmt.Errorf("failed to get feed: %w", err)
+		return database.Feed{}, fmt.Errorf("failed to get feed: %w", err)
 	}
 
-	err = s.db.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
+	err = db.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{
 		FeedID: feed.ID,
 		UserID: user.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to delete feed follow: %w", err)
+		return database.Feed{}, fmt.Errorf("failed to delete feed follow: %w", err)
 	}
-
-	fmt.Printf("Feed: %s Unfollowed by: %s\n", feed.Name, user.Name)
-	return nil
+	return feed, nil
 }
 
 func main() {
@@ -317,6 +551,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	s.conn = db
 	s.db = database.New(db)
 
 	commands := &commands{
@@ -324,15 +559,40 @@ func main() {
 	}
 	commands.register("login", handlerLogin)
 	commands.register("register", handlerRegister)
-	commands.register("reset", handlerReset)
+	commands.register("logout", handlerLogout)
+	commands.register("bootstrap", handlerBootstrap)
+	commands.register("passwd", middlewareLoggedIn(handlerPasswd))
+	commands.register("reset", middlewareAdmin(handlerReset))
 	commands.register("users", handlerUsers)
+	commands.register("role", middlewareAdmin(handlerRole))
+	commands.register("user", middlewareLoggedIn(handlerUser))
+	commands.register("deleteuser", middlewareAdmin(handlerUserDelete))
 	commands.register("agg", handlerAgg)
 	commands.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	commands.register("feeds", handlerFeeds)
+	commands.register("feed", middlewareLoggedIn(handlerFeed))
+	commands.register("deletefeed", middlewareAdmin(handlerFeedDelete))
 	commands.register("follow", middlewareLoggedIn(handlerFollow))
 	commands.register("following", middlewareLoggedIn(handlerFollowing))
+	commands.register("tag", middlewareLoggedIn(handlerTag))
+	commands.register("untag", middlewareLoggedIn(handlerUntag))
 	commands.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	commands.register("follow-set", middlewareLoggedIn(handlerFollowSet))
 	commands.register("browse", middlewareLoggedIn(handlerBrowse))
+	commands.register("filter", middlewareLoggedIn(handlerFilter))
+	commands.register("post", handlerPost)
+	commands.register("read", middlewareLoggedIn(handlerRead))
+	commands.register("unread", middlewareLoggedIn(handlerUnread))
+	commands.register("star", middlewareLoggedIn(handlerStar))
+	commands.register("unstar", middlewareLoggedIn(handlerUnstar))
+	commands.register("prune", middlewareAdmin(handlerPrune))
+	commands.register("podcast", middlewareLoggedIn(handlerPodcast))
+	commands.register("serve", handlerServe)
+	commands.register("export-feed", middlewareLoggedIn(handlerExportFeed))
+	commands.register("feed-token", middlewareLoggedIn(handlerFeedToken))
+	commands.register("export-opml", middlewareLoggedIn(handlerExportOPML))
+	commands.register("digest", middlewareLoggedIn(handlerDigest))
+	commands.register("fever-password", middlewareLoggedIn(handlerFeverPassword))
 	userArgs := os.Args
 	if len(userArgs) < 2 {
 		fmt.Println("not enough arguments")
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, time.Hour},
+		{1, 2 * time.Hour},
+		{2, 4 * time.Hour},
+		{4, 16 * time.Hour},
+		{5, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.failures, time.Hour, 24*time.Hour); got != tt.want {
+			t.Errorf("retryAfter(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
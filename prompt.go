@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks the user to type "yes" before a destructive command goes
+// ahead, and returns an error naming action if they do not.
+func confirm(action string) error {
+	fmt.Print("Type \"yes\" to continue: ")
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return fmt.Errorf("%s cancelled", action)
+	}
+	return nil
+}
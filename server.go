@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/rss"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const sessionCookieName = "gator_session"
+
+// serverStore is the part of *database.Queries used by the handlers that are
+// tested without Postgres.
+type serverStore interface {
+	apiStore
+	webSubStore
+}
+
+var _ serverStore = (*database.Queries)(nil)
+
+type server struct {
+	s         *state
+	store     serverStore
+	templates *template.Template
+	fetcher   *rss.Fetcher
+	// publicURL is where hubs reach this server; WebSub is off without it.
+	publicURL string
+}
+
+func handlerServe(s *state, cmd command) error {
+	fs := newFlagSet(cmd)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	publicURL := fs.String("public-url", "", "URL this server is reachable at, used to receive WebSub pushes")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("serve does not take any arguments")
+	}
+
+	srv, err := newServer(s)
+	if err != nil {
+		return err
+	}
+	srv.publicURL = *publicURL
+	if srv.publicURL != "" {
+		go srv.subscribeWebSub(context.Background())
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving on %s\n", *addr)
+	return httpServer.ListenAndServe()
+}
+
+func newServer(s *state) (*server, error) {
+	templates, err := template.ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	fetcher, err := fetcherFromConfig(s.cfg.Fetch)
+	if err != nil {
+		return nil, err
+	}
+	return &server{s: s, store: s.db, templates: templates, fetcher: fetcher}, nil
+}
+
+func (srv *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", srv.requireUser(srv.handleIndex))
+	mux.HandleFunc("GET /login", srv.handleLoginForm)
+	mux.HandleFunc("POST /login", srv.handleLogin)
+	mux.HandleFunc("POST /logout", srv.handleLogout)
+	mux.HandleFunc("POST /feeds", srv.requireUser(srv.handleAddFeed))
+	mux.HandleFunc("POST /follow", srv.requireUser(srv.handleFollow))
+	mux.HandleFunc("POST /unfollow", srv.requireUser(srv.handleUnfollow))
+	mux.HandleFunc("POST /posts/{id}/{action}", srv.requireUser(srv.handlePostAction))
+	mux.HandleFunc("GET /users/{name}/{format}", srv.handleTimelineFeed)
+	srv.apiRoutes(mux)
+	mux.HandleFunc("/fever/", srv.handleFever)
+	mux.HandleFunc("GET /websub/{id}", srv.handleWebSubVerify)
+	mux.HandleFunc("POST /websub/{id}", srv.handleWebSubPush)
+	return mux
+}
+
+// requireUser is the web counterpart of middlewareLoggedIn: it resolves the
+// user whose session token is in the session cookie, or sends the browser to
+// the login page.
+func (srv *server) requireUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := sessionUser(r.Context(), srv.s.db, cookie.Value)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// render writes the named template with the given status. The page is
+// rendered before anything is written, so a template error can still be
+// reported as a server error.
+func (srv *server) render(w http.ResponseWriter, status int, name string, data any) {
+	var page bytes.Buffer
+	if err := srv.templates.ExecuteTemplate(&page, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	page.WriteTo(w)
+}
+
+type feedListing struct {
+	database.Feed
+	Followed bool
+}
+
+type indexPage struct {
+	User      database.User
+	ShowAll   bool
+	Posts     []database.GetPostsForUserRow
+	Following []database.GetFeedFollowsForUserRow
+	Feeds     []feedListing
+}
+
+func (srv *server) handleIndex(w http.ResponseWriter, r *http.Request, user database.User) {
+	ctx := r.Context()
+	page := indexPage{User: user, ShowAll: r.URL.Query().Get("all") != ""}
+
+	posts, err := srv.s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID:       user.ID,
+		UnreadOnly:   !page.ShowAll,
+		ApplyFilters: true,
+		Limit:        50,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get posts: %s", err), http.StatusInternalServerError)
+		return
+	}
+	page.Posts = posts
+
+	follows, err := srv.s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get feed follows: %s", err), http.StatusInternalServerError)
+		return
+	}
+	page.Following = follows
+
+	feeds, err := srv.s.db.ListFeeds(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list feeds: %s", err), http.StatusInternalServerError)
+		return
+	}
+	followed := make(map[uuid.UUID]bool)
+	for _, follow := range follows {
+		followed[follow.FeedID] = true
+	}
+	for _, feed := range feeds {
+		page.Feeds = append(page.Feeds, feedListing{Feed: feed, Followed: followed[feed.ID]})
+	}
+
+	srv.render(w, http.StatusOK, "index.html", page)
+}
+
+func (srv *server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	srv.render(w, http.StatusOK, "login.html", nil)
+}
+
+func (srv *server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := srv.s.db.GetUser(ctx, r.FormValue("name"))
+	if err == nil {
+		err = authenticate(user, r.FormValue("password"))
+	}
+	if err != nil {
+		srv.render(w, http.StatusUnauthorized, "login.html", "invalid user name or password")
+		return
+	}
+
+	token, session, err := createSession(ctx, srv.s.db, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (srv *server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := srv.s.db.DeleteSession(r.Context(), auth.HashToken(cookie.Value)); err != nil {
+			http.Error(w, fmt.Sprintf("failed to delete session: %s", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookieName,
+		Path:   "/",
+		MaxAge: -1,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (srv *server) handleAddFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	_, _, err := addFeed(r.Context(), srv.s.db, user, r.FormValue("name"), r.FormValue("url"))
+	srv.redirectHome(w, r, err)
+}
+
+func (srv *server) handleFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	_, err := followFeed(r.Context(), srv.s.db, user, r.FormValue("url"))
+	srv.redirectHome(w, r, err)
+}
+
+func (srv *server) handleUnfollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	_, err := unfollowFeed(r.Context(), srv.s.db, user, r.FormValue("url"))
+	srv.redirectHome(w, r, err)
+}
+
+func (srv *server) handlePostAction(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse post ID: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	switch r.PathValue("action") {
+	case "read":
+		err = setPostRead(ctx, srv.s.db, user, postID, true)
+	case "unread":
+		err = setPostRead(ctx, srv.s.db, user, postID, false)
+	case "star":
+		err = setPostStarred(ctx, srv.s.db, user, postID, true)
+	case "unstar":
+		err = setPostStarred(ctx, srv.s.db, user, postID, false)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	srv.redirectHome(w, r, err)
+}
+
+// timelineAllowed reports whether r may read the timeline feeds of user: it
+// must carry the user's feed token, or come from a browser logged in as the
+// user.
+func (srv *server) timelineAllowed(r *http.Request, user database.User) bool {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return user.FeedTokenHash.Valid &&
+			subtle.ConstantTimeCompare([]byte(auth.HashToken(token)), []byte(user.FeedTokenHash.String)) == 1
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return false
+	}
+	sessionUser, err := sessionUser(r.Context(), srv.s.db, cookie.Value)
+	return err == nil && sessionUser.ID == user.ID
+}
+
+// handleTimelineFeed republishes a user's timeline as an RSS or Atom feed,
+// optionally limited to one feed with ?feed=<feed id> or to the feeds with a
+// tag with ?tag=<tag>. Feed readers authenticate with ?token=<feed token>;
+// without one the request needs the user's session cookie. Timelines the
+// request may not read are reported as not found.
+func (srv *server) handleTimelineFeed(w http.ResponseWriter, r *http.Request) {
+	format := r.PathValue("format")
+	contentType := map[string]string{
+		"rss":  "application/rss+xml; charset=utf-8",
+		"atom": "application/atom+xml; charset=utf-8",
+	}[format]
+	if contentType == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	user, err := srv.s.db.GetUser(ctx, r.PathValue("name"))
+	if err != nil || !srv.timelineAllowed(r, user) {
+		http.NotFound(w, r)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	opts := timelineOptions{
+		limit:   defaultExportLimit,
+		link:    scheme + "://" + r.Host + "/",
+		selfURL: scheme + "://" + r.Host + r.URL.RequestURI(),
+	}
+	if v := r.URL.Query().Get("feed"); v != "" {
+		feedID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse feed ID: %s", err), http.StatusBadRequest)
+			return
+		}
+		opts.feedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	opts.tag = r.URL.Query().Get("tag")
+
+	timeline, err := userTimeline(ctx, srv.s.db, user, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "atom" {
+		err = rss.WriteAtom(w, timeline)
+	} else {
+		err = rss.WriteRSS(w, timeline)
+	}
+	if err != nil {
+		fmt.Printf("failed to write %s feed: %v\n", format, err)
+	}
+}
+
+// redirectHome finishes a form submission, returning to the page the form
+// was on.
+func (srv *server) redirectHome(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target := "/"
+	if r.FormValue("all") != "" {
+		target = "/?all=1"
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
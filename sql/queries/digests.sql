@@ -0,0 +1,56 @@
+-- name: UpsertDigestSchedule :one
+INSERT INTO digest_schedules (id, created_at, updated_at, user_id, email, frequency)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email,
+    frequency = EXCLUDED.frequency,
+    updated_at = EXCLUDED.updated_at,
+    failures = 0,
+    retry_at = NULL
+RETURNING *;
+
+-- name: DeleteDigestSchedule :execrows
+DELETE FROM digest_schedules
+WHERE user_id = $1;
+
+-- name: ListDueDigestSchedules :many
+SELECT digest_schedules.*, users.name AS user_name
+FROM digest_schedules
+INNER JOIN users ON users.id = digest_schedules.user_id
+WHERE (
+    digest_schedules.last_sent_at IS NULL
+    OR (digest_schedules.frequency = 'daily' AND digest_schedules.last_sent_at <= sqlc.arg(daily_before))
+    OR (digest_schedules.frequency = 'weekly' AND digest_schedules.last_sent_at <= sqlc.arg(weekly_before))
+)
+AND (digest_schedules.retry_at IS NULL OR digest_schedules.retry_at <= sqlc.arg(now));
+
+-- name: SetDigestSent :exec
+UPDATE digest_schedules
+SET last_sent_at = $2, failures = 0, retry_at = NULL
+WHERE id = $1;
+
+-- name: SetDigestFailed :exec
+UPDATE digest_schedules
+SET failures = failures + 1, retry_at = $2
+WHERE id = $1;
+
+-- name: GetDigestPosts :many
+SELECT
+    posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.created_at >= sqlc.arg(since)
+AND NOT feed_follows.muted
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM filters
+    WHERE filters.user_id = sqlc.arg(user_id) AND post_matches_filter(posts, filters)
+)
+ORDER BY feed_follows.priority DESC, feed_name, posts.feed_id, posts.published_at DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
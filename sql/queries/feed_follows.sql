@@ -13,12 +13,45 @@ INNER JOIN feeds ON feeds.id = inserted_feed_follow.feed_id
 INNER JOIN users ON users.id = inserted_feed_follow.user_id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name
+SELECT
+    feed_follows.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 INNER JOIN users ON users.id = feed_follows.user_id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.priority DESC, feed_name;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: UpdateFeedFollowSettings :exec
+UPDATE feed_follows
+SET display_name = $3, priority = $4, muted = $5, updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: AddFeedFollowTags :execrows
+UPDATE feed_follows
+SET tags = ARRAY(SELECT DISTINCT unnest(tags || sqlc.arg(tags)::text[]) ORDER BY 1), updated_at = NOW()
+WHERE feed_id = sqlc.arg(feed_id) AND user_id = sqlc.arg(user_id);
+
+-- name: RemoveFeedFollowTags :execrows
+UPDATE feed_follows
+SET tags = ARRAY(SELECT unnest(tags) EXCEPT SELECT unnest(sqlc.arg(tags)::text[]) ORDER BY 1), updated_at = NOW()
+WHERE feed_id = sqlc.arg(feed_id) AND user_id = sqlc.arg(user_id);
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
-WHERE feed_id = $1 AND user_id = $2;
\ No newline at end of file
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: SetFeedFollowAutoDownload :execrows
+UPDATE feed_follows
+SET auto_download_since = $3, updated_at = NOW()
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1;
\ No newline at end of file
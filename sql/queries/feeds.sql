@@ -7,17 +7,138 @@ RETURNING *;
 SELECT * FROM feeds
 ORDER BY created_at DESC;
 
+-- name: ListFeedsPage :many
+SELECT * FROM feeds
+ORDER BY created_at DESC
+LIMIT $1
+OFFSET $2;
+
 -- name: GetFeedByURL :one
 SELECT * FROM feeds
 WHERE url = $1
 LIMIT 1;
 
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
 -- name: MarkFeedAsFetched :exec
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET last_fetched_at = NOW(), next_fetch_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2
 WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE deactivated_at IS NULL
+  AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+      AND websub_subscriptions.state = 'active'
+      AND websub_subscriptions.lease_expires_at > NOW()
+      AND feeds.last_fetched_at > NOW() - INTERVAL '1 day'
+  )
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: ResetFeeds :execrows
+DELETE FROM feeds;
+
+-- name: DeleteFeedByURL :execrows
+DELETE FROM feeds
+WHERE url = $1;
+
+-- name: TransferFeedOwnership :exec
+UPDATE feeds
+SET user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+), updated_at = NOW()
+WHERE feeds.user_id = $1;
+
+-- name: RenameFeed :execrows
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE url = $1;
+
+-- name: SetFeedURL :execrows
+UPDATE feeds
+SET url = sqlc.arg('new_url'),
+    redirect_url = NULL,
+    redirect_count = 0,
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE url = sqlc.arg('old_url');
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE
+        WHEN redirect_url = sqlc.arg('redirect_url')::text THEN redirect_count + 1
+        ELSE 1
+    END,
+    redirect_url = sqlc.arg('redirect_url')::text,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING redirect_count;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1 AND redirect_url IS NOT NULL;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET parse_warnings = $2
+WHERE id = $1;
+
+-- name: MergeFeedFollows :exec
+INSERT INTO feed_follows (
+    id, created_at, updated_at, feed_id, user_id, auto_download_since, tags, display_name, priority, muted
+)
+SELECT
+    gen_random_uuid(), created_at, NOW(), sqlc.arg('to_feed_id')::uuid, user_id, auto_download_since, tags, display_name, priority, muted
+FROM feed_follows
+WHERE feed_id = sqlc.arg('from_feed_id')::uuid
+ON CONFLICT (feed_id, user_id) DO NOTHING;
+
+-- name: MergeFeedPosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg('to_feed_id')::uuid
+WHERE posts.feed_id = sqlc.arg('from_feed_id')::uuid
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = sqlc.arg('to_feed_id')::uuid
+    AND existing.item_key = posts.item_key
+);
+
+-- name: MergePostReads :exec
+INSERT INTO post_reads (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), post_reads.created_at, NOW(), existing.id, post_reads.user_id
+FROM post_reads
+INNER JOIN posts ON posts.id = post_reads.post_id
+INNER JOIN posts AS existing ON existing.item_key = posts.item_key
+WHERE posts.feed_id = sqlc.arg('from_feed_id')::uuid
+AND existing.feed_id = sqlc.arg('to_feed_id')::uuid
+ON CONFLICT (post_id, user_id) DO NOTHING;
+
+-- name: MergePostStars :exec
+INSERT INTO post_stars (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), post_stars.created_at, NOW(), existing.id, post_stars.user_id
+FROM post_stars
+INNER JOIN posts ON posts.id = post_stars.post_id
+INNER JOIN posts AS existing ON existing.item_key = posts.item_key
+WHERE posts.feed_id = sqlc.arg('from_feed_id')::uuid
+AND existing.feed_id = sqlc.arg('to_feed_id')::uuid
+ON CONFLICT (post_id, user_id) DO NOTHING;
\ No newline at end of file
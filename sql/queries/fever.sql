@@ -0,0 +1,123 @@
+-- name: SetUserFeverAPIKey :exec
+UPDATE users
+SET fever_api_key = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserByFeverAPIKey :one
+SELECT * FROM users
+WHERE fever_api_key = $1 LIMIT 1;
+
+-- name: ListFeverFeeds :many
+SELECT feeds.fever_id, feeds.name, feeds.url, feeds.last_fetched_at
+FROM feeds
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name;
+
+-- name: ListFeverItems :many
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.url,
+    posts.author,
+    posts.description,
+    posts.content,
+    posts.published_at,
+    posts.created_at,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+    ) AS read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
+    ) AS starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND posts.fever_id > sqlc.arg('since_id')::bigint
+AND (sqlc.arg('max_id')::bigint = 0 OR posts.fever_id < sqlc.arg('max_id')::bigint)
+AND (
+    cardinality(sqlc.arg('with_ids')::bigint[]) = 0
+    OR posts.fever_id = ANY(sqlc.arg('with_ids')::bigint[])
+)
+AND (
+    (
+        NOT feed_follows.muted
+        AND NOT EXISTS (
+            SELECT 1 FROM filters
+            WHERE filters.user_id = sqlc.arg('user_id') AND post_matches_filter(posts, filters)
+        )
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
+    )
+)
+ORDER BY CASE WHEN sqlc.arg('max_id')::bigint = 0 THEN posts.fever_id ELSE -posts.fever_id END
+LIMIT sqlc.arg('limit');
+
+-- name: CountFeverItems :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND (
+    (
+        NOT feed_follows.muted
+        AND NOT EXISTS (
+            SELECT 1 FROM filters
+            WHERE filters.user_id = $1 AND post_matches_filter(posts, filters)
+        )
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1
+    )
+);
+
+-- name: ListUnreadFeverItemIDs :many
+SELECT posts.fever_id FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+)
+AND (
+    (
+        NOT feed_follows.muted
+        AND NOT EXISTS (
+            SELECT 1 FROM filters
+            WHERE filters.user_id = $1 AND post_matches_filter(posts, filters)
+        )
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1
+    )
+)
+ORDER BY posts.fever_id;
+
+-- name: ListSavedFeverItemIDs :many
+SELECT posts.fever_id FROM posts
+INNER JOIN post_stars ON post_stars.post_id = posts.id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = post_stars.user_id
+WHERE post_stars.user_id = $1
+ORDER BY posts.fever_id;
+
+-- name: GetPostIDByFeverID :one
+SELECT id FROM posts
+WHERE fever_id = $1;
+
+-- name: MarkFeverPostsRead :exec
+INSERT INTO post_reads (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), NOW(), NOW(), posts.id, feed_follows.user_id
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND (sqlc.arg('feed_fever_id')::bigint = 0 OR feeds.fever_id = sqlc.arg('feed_fever_id')::bigint)
+AND posts.created_at <= sqlc.arg('before')
+ON CONFLICT (post_id, user_id) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- name: CreateFilter :one
+INSERT INTO filters (id, created_at, user_id, feed_id, field, pattern, regex, mark_read)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListFiltersForUser :many
+SELECT filters.*, feeds.name AS feed_name
+FROM filters
+LEFT JOIN feeds ON feeds.id = filters.feed_id
+WHERE filters.user_id = $1
+ORDER BY filters.created_at;
+
+-- name: DeleteFilter :execrows
+DELETE FROM filters
+WHERE id = $1 AND user_id = $2;
+
+-- name: CheckFilterRegex :one
+SELECT '' ~* sqlc.arg(pattern)::text AS matches;
+
+-- name: MarkFilteredPostRead :exec
+INSERT INTO post_reads (id, created_at, updated_at, post_id, user_id)
+SELECT gen_random_uuid(), NOW(), NOW(), posts.id, filters.user_id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN filters ON filters.user_id = feed_follows.user_id
+WHERE posts.id = $1
+AND filters.mark_read
+AND post_matches_filter(posts, filters)
+ON CONFLICT (post_id, user_id) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- name: CreatePostEdit :exec
+INSERT INTO post_edits (id, created_at, post_id, title, description, content_hash)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListPostEdits :many
+SELECT * FROM post_edits
+WHERE post_id = $1
+ORDER BY created_at DESC;
\ No newline at end of file
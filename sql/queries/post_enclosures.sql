@@ -0,0 +1,47 @@
+-- name: UpsertPostEnclosure :one
+INSERT INTO post_enclosures (id, created_at, updated_at, post_id, url, mime_type, length)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (post_id, url) DO UPDATE
+SET mime_type = EXCLUDED.mime_type,
+    length = EXCLUDED.length,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: DeleteStalePostEnclosures :exec
+DELETE FROM post_enclosures
+WHERE post_id = sqlc.arg('post_id') AND NOT (url = ANY(sqlc.arg('urls')::text[]));
+
+-- name: ListPostEnclosures :many
+SELECT * FROM post_enclosures
+WHERE post_id = $1
+ORDER BY created_at;
+
+-- name: MarkEnclosureDownloaded :exec
+UPDATE post_enclosures
+SET downloaded_at = NOW(), updated_at = NOW(), local_path = $2, download_failures = 0, download_retry_at = NULL
+WHERE id = $1;
+
+-- name: MarkEnclosureDownloadFailed :exec
+UPDATE post_enclosures
+SET download_failures = download_failures + 1, download_retry_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListPendingAutoDownloads :many
+SELECT
+    post_enclosures.*,
+    posts.title AS post_title,
+    feeds.name AS feed_name
+FROM post_enclosures
+INNER JOIN posts ON posts.id = post_enclosures.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE post_enclosures.downloaded_at IS NULL
+AND post_enclosures.download_failures < sqlc.arg(max_failures)
+AND (post_enclosures.download_retry_at IS NULL OR post_enclosures.download_retry_at <= sqlc.arg(now))
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id
+    AND feed_follows.auto_download_since IS NOT NULL
+    AND posts.created_at >= feed_follows.auto_download_since
+)
+ORDER BY posts.created_at
+LIMIT sqlc.arg('limit');
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (id, created_at, updated_at, post_id, user_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (post_id, user_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE post_id = $1 AND user_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO post_stars (id, created_at, updated_at, post_id, user_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (post_id, user_id) DO NOTHING;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE post_id = $1 AND user_id = $2;
\ No newline at end of file
@@ -1,12 +1,137 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+-- name: UpsertPost :one
+INSERT INTO posts (
+    id, created_at, updated_at, title, url, description, published_at, feed_id,
+    item_key, content_hash, summary, content, author, categories, comments_url,
+    duration_seconds, episode, season, image_url
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+    $11, $12, $13, $14, $15, $16, $17, $18, $19
+)
+ON CONFLICT (feed_id, item_key) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    summary = EXCLUDED.summary,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    categories = EXCLUDED.categories,
+    comments_url = EXCLUDED.comments_url,
+    duration_seconds = EXCLUDED.duration_seconds,
+    episode = EXCLUDED.episode,
+    season = EXCLUDED.season,
+    image_url = EXCLUDED.image_url,
+    content_hash = EXCLUDED.content_hash,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.content_hash <> EXCLUDED.content_hash
 RETURNING *;
 
--- name: GetPostsForUser :many
+-- name: GetPostByItemKey :one
 SELECT * FROM posts
-WHERE feed_id IN (
-    SELECT id FROM feeds WHERE user_id = $1
+WHERE feed_id = $1 AND item_key = $2
+LIMIT 1;
+
+-- name: AdoptPost :one
+UPDATE posts
+SET item_key = sqlc.arg(item_key)
+WHERE id = (
+    SELECT id FROM posts AS legacy
+    WHERE legacy.feed_id = sqlc.arg(feed_id)
+    AND legacy.item_key = ANY(sqlc.arg(legacy_keys)::text[])
+    ORDER BY legacy.created_at
+    LIMIT 1
 )
-ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetPostDetails :one
+SELECT posts.*, feeds.name AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id = $1;
+
+-- name: GetPostsForUser :many
+SELECT
+    posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    EXISTS (SELECT 1 FROM post_edits WHERE post_edits.post_id = posts.id) AS updated,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+    ) AS read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
+    ) AS starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+AND (
+    NOT sqlc.arg('unread_only')::boolean
+    OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+    )
+)
+AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id')::uuid)
+AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag')::text = ANY(feed_follows.tags))
+AND (
+    NOT sqlc.arg('apply_filters')::boolean
+    OR (
+        NOT feed_follows.muted
+        AND NOT EXISTS (
+            SELECT 1 FROM filters
+            WHERE filters.user_id = sqlc.arg('user_id') AND post_matches_filter(posts, filters)
+        )
+    )
+)
+ORDER BY
+    CASE WHEN sqlc.arg('by_priority')::boolean THEN feed_follows.priority ELSE 0 END DESC,
+    posts.published_at DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: PrunePosts :many
+WITH ranked AS (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (
+            PARTITION BY feed_id
+            ORDER BY published_at DESC NULLS LAST, created_at DESC
+        ) AS position
+    FROM posts
+),
+pruned AS (
+    DELETE FROM posts
+    USING ranked
+    WHERE posts.id = ranked.id
+    AND (
+        COALESCE(posts.published_at, posts.created_at) < sqlc.narg('older_than')::timestamp
+        OR (sqlc.arg('max_per_feed')::bigint > 0 AND ranked.position > sqlc.arg('max_per_feed')::bigint)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id
+    )
+    AND (
+        NOT sqlc.arg('keep_unread')::boolean
+        OR NOT EXISTS (
+            SELECT 1 FROM feed_follows
+            WHERE feed_follows.feed_id = posts.feed_id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_reads
+                WHERE post_reads.post_id = posts.id
+                AND post_reads.user_id = feed_follows.user_id
+            )
+        )
+    )
+    RETURNING posts.feed_id
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS removed
+FROM pruned
+INNER JOIN feeds ON feeds.id = pruned.feed_id
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name;
+
+-- name: ResetPosts :execrows
+DELETE FROM posts;
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, expires_at, user_id, token_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUserBySessionToken :one
+SELECT users.* FROM sessions
+INNER JOIN users ON users.id = sessions.user_id
+WHERE sessions.token_hash = $1 AND sessions.expires_at > NOW()
+LIMIT 1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE expires_at <= NOW();
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: UpsertPasswordReset :exec
+INSERT INTO password_resets (id, created_at, expires_at, user_id, token_hash)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    expires_at = EXCLUDED.expires_at,
+    token_hash = EXCLUDED.token_hash;
+
+-- name: GetPasswordReset :one
+SELECT * FROM password_resets
+WHERE user_id = $1 AND expires_at > NOW();
+
+-- name: DeletePasswordReset :exec
+DELETE FROM password_resets
+WHERE user_id = $1;
\ No newline at end of file
@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'user' ELSE 'admin' END
 )
 RETURNING *;
 
@@ -16,9 +18,47 @@ WHERE name = $1 LIMIT 1;
 SELECT * FROM users
 WHERE id = $1 LIMIT 1;
 
--- name: ResetUsers :exec
+-- name: ResetUsers :execrows
 DELETE FROM users;
 
+-- name: DeleteUserByName :execrows
+DELETE FROM users
+WHERE name = $1;
+
 -- name: ListUsers :many
 SELECT * FROM users
-ORDER BY id;
\ No newline at end of file
+ORDER BY id;
+
+-- name: ListUsersPage :many
+SELECT * FROM users
+ORDER BY name
+LIMIT $1
+OFFSET $2;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE name = $1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
+
+-- name: RenameUser :execrows
+UPDATE users
+SET name = sqlc.arg('new_name'), fever_api_key = NULL, updated_at = NOW()
+WHERE name = sqlc.arg('old_name');
+
+-- name: SetUserFeedToken :exec
+UPDATE users
+SET feed_token_hash = $2
+WHERE id = $1;
+
+-- name: CountUsersWithPassword :one
+SELECT COUNT(*) FROM users
+WHERE password_hash IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,42 @@
+-- name: SetFeedHub :exec
+UPDATE feeds
+SET hub_url = $2, topic_url = $3
+WHERE id = $1;
+
+-- name: ListFeedsDueForWebSub :many
+SELECT feeds.id, feeds.url, feeds.hub_url, feeds.topic_url FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.hub_url IS NOT NULL
+  AND feeds.deactivated_at IS NULL
+  AND (
+    websub_subscriptions.id IS NULL
+    OR websub_subscriptions.hub_url <> feeds.hub_url
+    OR websub_subscriptions.topic_url <> feeds.topic_url
+    OR (websub_subscriptions.state = 'active' AND websub_subscriptions.renew_at < sqlc.arg(now))
+    OR (websub_subscriptions.state = 'pending' AND websub_subscriptions.updated_at < sqlc.arg(retry_pending_before))
+    OR (websub_subscriptions.state = 'denied' AND websub_subscriptions.updated_at < sqlc.arg(retry_denied_before))
+  );
+
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    state = 'pending',
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE id = $1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active', lease_expires_at = $2, renew_at = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DenyWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'denied', lease_expires_at = NULL, renew_at = NULL, updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN item_key TEXT;
+UPDATE posts SET item_key = url;
+ALTER TABLE posts ALTER COLUMN item_key SET NOT NULL;
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_item_key_key UNIQUE (feed_id, item_key);
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_item_key_key;
+-- Posts are unique per feed now, so the same URL may be stored more than
+-- once. Only the oldest copy of each URL survives the downgrade.
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at, id) AS position
+        FROM posts
+    ) AS ranked
+    WHERE position > 1
+);
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts DROP COLUMN item_key;
\ No newline at end of file
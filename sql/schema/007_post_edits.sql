@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_edits (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT,
+    content_hash TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE post_edits;
+ALTER TABLE posts DROP COLUMN content_hash;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN summary TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN summary;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT;
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE posts ADD COLUMN comments_url TEXT;
+
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE post_enclosures;
+ALTER TABLE posts DROP COLUMN comments_url;
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
+ALTER TABLE posts DROP COLUMN content;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN duration_seconds INTEGER;
+ALTER TABLE posts ADD COLUMN episode INTEGER;
+ALTER TABLE posts ADD COLUMN season INTEGER;
+ALTER TABLE posts ADD COLUMN image_url TEXT;
+
+ALTER TABLE post_enclosures ADD COLUMN downloaded_at TIMESTAMP;
+ALTER TABLE post_enclosures ADD COLUMN local_path TEXT;
+
+ALTER TABLE feed_follows ADD COLUMN auto_download_since TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN auto_download_since;
+
+ALTER TABLE post_enclosures DROP COLUMN local_path;
+ALTER TABLE post_enclosures DROP COLUMN downloaded_at;
+
+ALTER TABLE posts DROP COLUMN image_url;
+ALTER TABLE posts DROP COLUMN season;
+ALTER TABLE posts DROP COLUMN episode;
+ALTER TABLE posts DROP COLUMN duration_seconds;
\ No newline at end of file
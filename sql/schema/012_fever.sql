@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN fever_api_key TEXT UNIQUE;
+
+-- The Fever API identifies feeds and items by integers.
+ALTER TABLE feeds ADD COLUMN fever_id BIGSERIAL UNIQUE;
+ALTER TABLE posts ADD COLUMN fever_id BIGSERIAL UNIQUE;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN fever_id;
+ALTER TABLE feeds DROP COLUMN fever_id;
+
+ALTER TABLE users DROP COLUMN fever_api_key;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts DROP CONSTRAINT fk_posts_feed_id;
+ALTER TABLE posts ADD CONSTRAINT fk_posts_feed_id
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT fk_posts_feed_id;
+ALTER TABLE posts ADD CONSTRAINT fk_posts_feed_id
+    FOREIGN KEY (feed_id) REFERENCES feeds(id);
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('admin', 'user'));
+
+-- The oldest existing user administers the installation.
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- Deleting the user who added a feed keeps the feed for its followers.
+ALTER TABLE feeds ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_fkey;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM feeds WHERE user_id IS NULL;
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_fkey;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE feeds ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE users DROP COLUMN role;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN redirect_url TEXT;
+ALTER TABLE feeds ADD COLUMN redirect_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN deactivated_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN deactivated_at;
+ALTER TABLE feeds DROP COLUMN redirect_count;
+ALTER TABLE feeds DROP COLUMN redirect_url;
\ No newline at end of file
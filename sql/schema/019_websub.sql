@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN hub_url TEXT;
+ALTER TABLE feeds ADD COLUMN topic_url TEXT;
+
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'active', 'denied')),
+    lease_expires_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
+
+ALTER TABLE feeds DROP COLUMN topic_url;
+ALTER TABLE feeds DROP COLUMN hub_url;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE filters (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    field TEXT NOT NULL CHECK (field IN ('text', 'title', 'description', 'author', 'category')),
+    pattern TEXT NOT NULL,
+    regex BOOLEAN NOT NULL DEFAULT FALSE,
+    mark_read BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX filters_user_id_idx ON filters (user_id);
+
+-- +goose StatementBegin
+CREATE FUNCTION post_matches_filter(p posts, f filters) RETURNS BOOLEAN AS $$
+    SELECT (f.feed_id IS NULL OR f.feed_id = p.feed_id)
+    AND EXISTS (
+        SELECT 1
+        FROM unnest(CASE f.field
+            WHEN 'title' THEN ARRAY[p.title]
+            WHEN 'description' THEN ARRAY[COALESCE(p.summary, '')]
+            WHEN 'author' THEN ARRAY[COALESCE(p.author, '')]
+            WHEN 'category' THEN p.categories
+            ELSE ARRAY[p.title, COALESCE(p.summary, '')]
+        END) AS value
+        WHERE CASE
+            WHEN f.regex THEN value ~* f.pattern
+            ELSE strpos(lower(value), lower(f.pattern)) > 0
+        END
+    )
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION post_matches_filter(posts, filters);
+DROP TABLE filters;
\ No newline at end of file
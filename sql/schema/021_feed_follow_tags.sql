@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feed_follows ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN tags;
\ No newline at end of file
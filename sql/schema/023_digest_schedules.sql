@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE digest_schedules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    last_sent_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE digest_schedules;
\ No newline at end of file
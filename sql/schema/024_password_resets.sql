@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE password_resets;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE websub_subscriptions ADD COLUMN renew_at TIMESTAMP;
+UPDATE websub_subscriptions SET renew_at = lease_expires_at - INTERVAL '1 day'
+WHERE state = 'active';
+
+-- +goose Down
+ALTER TABLE websub_subscriptions DROP COLUMN renew_at;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE digest_schedules ADD COLUMN failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE digest_schedules ADD COLUMN retry_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE digest_schedules DROP COLUMN retry_at;
+ALTER TABLE digest_schedules DROP COLUMN failures;
\ No newline at end of file
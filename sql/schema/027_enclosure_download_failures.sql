@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE post_enclosures ADD COLUMN download_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE post_enclosures ADD COLUMN download_retry_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE post_enclosures DROP COLUMN download_retry_at;
+ALTER TABLE post_enclosures DROP COLUMN download_failures;
\ No newline at end of file
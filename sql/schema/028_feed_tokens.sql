@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN feed_token_hash TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN feed_token_hash;
\ No newline at end of file
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+)
+
+// memStore is an in-memory apiStore.
+type memStore struct {
+	users    map[string]database.User
+	sessions map[string]uuid.UUID
+	feeds    []database.Feed
+	follows  []database.GetFeedFollowsForUserRow
+	posts    map[uuid.UUID]database.GetPostDetailsRow
+	reads    map[uuid.UUID]bool
+	stars    map[uuid.UUID]bool
+	subs     map[uuid.UUID]database.WebsubSubscription
+	// saved holds the posts stored by rss.SavePosts, by item key.
+	saved map[string]database.Post
+	// err is returned by the list queries when set.
+	err error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		users:    map[string]database.User{},
+		sessions: map[string]uuid.UUID{},
+		posts:    map[uuid.UUID]database.GetPostDetailsRow{},
+		reads:    map[uuid.UUID]bool{},
+		stars:    map[uuid.UUID]bool{},
+		subs:     map[uuid.UUID]database.WebsubSubscription{},
+		saved:    map[string]database.Post{},
+	}
+}
+
+func (m *memStore) GetUser(_ context.Context, name string) (database.User, error) {
+	user, ok := m.users[name]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *memStore) GetUserBySessionToken(_ context.Context, tokenHash string) (database.User, error) {
+	userID, ok := m.sessions[tokenHash]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	for _, user := range m.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *memStore) ListUsersPage(_ context.Context, arg database.ListUsersPageParams) ([]database.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var users []database.User
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	return page(users, arg.Limit, arg.Offset), nil
+}
+
+func (m *memStore) CreateSession(_ context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	m.sessions[arg.TokenHash] = arg.UserID
+	return database.Session{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+	}, nil
+}
+
+func (m *memStore) DeleteSession(_ context.Context, tokenHash string) error {
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+func (m *memStore) CreateFeed(_ context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	m.feeds = append(m.feeds, feed)
+	return feed, nil
+}
+
+func (m *memStore) GetFeedByURL(_ context.Context, url string) (database.Feed, error) {
+	for _, feed := range m.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) ListFeedsPage(_ context.Context, arg database.ListFeedsPageParams) ([]database.Feed, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return page(m.feeds, arg.Limit, arg.Offset), nil
+}
+
+func (m *memStore) CreateFeedFollow(_ context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	for _, follow := range m.follows {
+		if follow.FeedID == arg.FeedID && follow.UserID == arg.UserID {
+			return database.CreateFeedFollowRow{}, errors.New("duplicate feed follow")
+		}
+	}
+	var feed database.Feed
+	for _, f := range m.feeds {
+		if f.ID == arg.FeedID {
+			feed = f
+		}
+	}
+	follow := database.GetFeedFollowsForUserRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		FeedID:    arg.FeedID,
+		UserID:    arg.UserID,
+		FeedName:  feed.Name,
+		FeedUrl:   feed.Url,
+	}
+	m.follows = append(m.follows, follow)
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		FeedID:    follow.FeedID,
+		UserID:    follow.UserID,
+		FeedName:  follow.FeedName,
+	}, nil
+}
+
+func (m *memStore) DeleteFeedFollow(_ context.Context, arg database.DeleteFeedFollowParams) error {
+	for i, follow := range m.follows {
+		if follow.FeedID == arg.FeedID && follow.UserID == arg.UserID {
+			m.follows = append(m.follows[:i], m.follows[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memStore) GetFeedFollowsForUser(_ context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	var follows []database.GetFeedFollowsForUserRow
+	for _, follow := range m.follows {
+		if follow.UserID == userID {
+			follows = append(follows, follow)
+		}
+	}
+	return follows, nil
+}
+
+func (m *memStore) GetPostDetails(_ context.Context, id uuid.UUID) (database.GetPostDetailsRow, error) {
+	post, ok := m.posts[id]
+	if !ok {
+		return database.GetPostDetailsRow{}, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+func (m *memStore) GetPostsForUser(_ context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var posts []database.GetPostsForUserRow
+	for _, post := range m.posts {
+		if arg.UnreadOnly && m.reads[post.ID] {
+			continue
+		}
+		posts = append(posts, database.GetPostsForUserRow{
+			ID:       post.ID,
+			Title:    post.Title,
+			Url:      post.Url,
+			FeedID:   post.FeedID,
+			FeedName: post.FeedName,
+			Read:     m.reads[post.ID],
+			Starred:  m.stars[post.ID],
+		})
+	}
+	return page(posts, arg.Limit, arg.Offset), nil
+}
+
+// errForeignKey stands in for the error Postgres reports when a read or star
+// refers to a post that does not exist.
+var errForeignKey = errors.New(`pq: insert or update on table "post_reads" violates foreign key constraint "post_reads_post_id_fkey"`)
+
+func (m *memStore) MarkPostRead(_ context.Context, arg database.MarkPostReadParams) error {
+	if _, ok := m.posts[arg.PostID]; !ok {
+		return errForeignKey
+	}
+	m.reads[arg.PostID] = true
+	return nil
+}
+
+func (m *memStore) MarkPostUnread(_ context.Context, arg database.MarkPostUnreadParams) error {
+	delete(m.reads, arg.PostID)
+	return nil
+}
+
+func (m *memStore) StarPost(_ context.Context, arg database.StarPostParams) error {
+	if _, ok := m.posts[arg.PostID]; !ok {
+		return errForeignKey
+	}
+	m.stars[arg.PostID] = true
+	return nil
+}
+
+func (m *memStore) UnstarPost(_ context.Context, arg database.UnstarPostParams) error {
+	delete(m.stars, arg.PostID)
+	return nil
+}
+
+func page[T any](items []T, limit, offset int32) []T {
+	items = items[min(int(offset), len(items)):]
+	return items[:min(int(limit), len(items))]
+}
+
+func (m *memStore) GetFeedByID(_ context.Context, id uuid.UUID) (database.Feed, error) {
+	for _, feed := range m.feeds {
+		if feed.ID == id {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) ListFeedsDueForWebSub(context.Context, database.ListFeedsDueForWebSubParams) ([]database.ListFeedsDueForWebSubRow, error) {
+	return nil, nil
+}
+
+func (m *memStore) UpsertWebSubSubscription(_ context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	sub := database.WebsubSubscription{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		FeedID:    arg.FeedID,
+		HubUrl:    arg.HubUrl,
+		TopicUrl:  arg.TopicUrl,
+		Secret:    arg.Secret,
+		State:     "pending",
+	}
+	m.subs[sub.ID] = sub
+	return sub, nil
+}
+
+func (m *memStore) GetWebSubSubscription(_ context.Context, id uuid.UUID) (database.WebsubSubscription, error) {
+	sub, ok := m.subs[id]
+	if !ok {
+		return database.WebsubSubscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (m *memStore) ActivateWebSubSubscription(_ context.Context, arg database.ActivateWebSubSubscriptionParams) error {
+	sub := m.subs[arg.ID]
+	sub.State = "active"
+	sub.LeaseExpiresAt = arg.LeaseExpiresAt
+	sub.RenewAt = arg.RenewAt
+	m.subs[arg.ID] = sub
+	return nil
+}
+
+func (m *memStore) DenyWebSubSubscription(_ context.Context, id uuid.UUID) error {
+	sub := m.subs[id]
+	sub.State = "denied"
+	sub.LeaseExpiresAt = sql.NullTime{}
+	sub.RenewAt = sql.NullTime{}
+	m.subs[id] = sub
+	return nil
+}
+
+func (m *memStore) GetPostByItemKey(_ context.Context, arg database.GetPostByItemKeyParams) (database.Post, error) {
+	post, ok := m.saved[arg.ItemKey]
+	if !ok || post.FeedID != arg.FeedID {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+func (m *memStore) AdoptPost(context.Context, database.AdoptPostParams) (database.Post, error) {
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *memStore) UpsertPost(_ context.Context, arg database.UpsertPostParams) (database.Post, error) {
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		FeedID:      arg.FeedID,
+		ItemKey:     arg.ItemKey,
+		ContentHash: arg.ContentHash,
+	}
+	m.saved[post.ItemKey] = post
+	return post, nil
+}
+
+func (m *memStore) CreatePostEdit(context.Context, database.CreatePostEditParams) error {
+	return nil
+}
+
+func (m *memStore) UpsertPostEnclosure(_ context.Context, arg database.UpsertPostEnclosureParams) (database.PostEnclosure, error) {
+	return database.PostEnclosure{ID: arg.ID, PostID: arg.PostID, Url: arg.Url}, nil
+}
+
+func (m *memStore) DeleteStalePostEnclosures(context.Context, database.DeleteStalePostEnclosuresParams) error {
+	return nil
+}
+
+func (m *memStore) MarkFilteredPostRead(context.Context, uuid.UUID) error {
+	return nil
+}
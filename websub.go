@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/auth"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/rss"
+	"github.com/jasonwashburn/gator/internal/websub"
+)
+
+// Feeds that announce a WebSub hub are subscribed to by gator serve, which
+// receives the hub's verification requests and content deliveries at
+// /websub/<subscription id>.
+const (
+	websubLeaseSeconds = 10 * 24 * 60 * 60
+	// A subscription is renewed once websubRenewPercent of the lease the hub
+	// granted has passed, however long or short the lease is.
+	websubRenewPercent = 80
+	// websubRetryPending is how long a hub has to verify a subscription
+	// before it is requested again.
+	websubRetryPending = time.Hour
+	websubRetryDenied  = 24 * time.Hour
+	websubInterval     = 5 * time.Minute
+)
+
+// webSubStore is the part of *database.Queries used to subscribe to hubs and
+// handle their requests.
+type webSubStore interface {
+	rss.PostStore
+	GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error)
+	ListFeedsDueForWebSub(ctx context.Context, arg database.ListFeedsDueForWebSubParams) ([]database.ListFeedsDueForWebSubRow, error)
+	UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error)
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (database.WebsubSubscription, error)
+	ActivateWebSubSubscription(ctx context.Context, arg database.ActivateWebSubSubscriptionParams) error
+	DenyWebSubSubscription(ctx context.Context, id uuid.UUID) error
+}
+
+// subscribeWebSub keeps a subscription with every hub that a feed announces,
+// renewing each before its lease runs out, until ctx is done.
+func (srv *server) subscribeWebSub(ctx context.Context) {
+	ticker := time.NewTicker(websubInterval)
+	defer ticker.Stop()
+	for {
+		if err := srv.renewWebSubSubscriptions(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("error renewing WebSub subscriptions: %s\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (srv *server) renewWebSubSubscriptions(ctx context.Context) error {
+	now := time.Now()
+	feeds, err := srv.store.ListFeedsDueForWebSub(ctx, database.ListFeedsDueForWebSubParams{
+		Now:                now,
+		RetryPendingBefore: now.Add(-websubRetryPending),
+		RetryDeniedBefore:  now.Add(-websubRetryDenied),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list feeds due for WebSub: %w", err)
+	}
+
+	for _, feed := range feeds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		secret, err := auth.NewToken()
+		if err != nil {
+			return err
+		}
+		sub, err := srv.store.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			FeedID:    feed.ID,
+			HubUrl:    feed.HubUrl.String,
+			TopicUrl:  feed.TopicUrl.String,
+			Secret:    secret,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save WebSub subscription: %w", err)
+		}
+
+		err = websub.Subscribe(ctx, srv.fetcher.Client(), websub.Subscription{
+			Hub:          sub.HubUrl,
+			Topic:        sub.TopicUrl,
+			Callback:     strings.TrimSuffix(srv.publicURL, "/") + "/websub/" + sub.ID.String(),
+			Secret:       sub.Secret,
+			LeaseSeconds: websubLeaseSeconds,
+		})
+		if err != nil {
+			fmt.Printf("failed to subscribe to %s at %s: %s\n", sub.TopicUrl, sub.HubUrl, err)
+			continue
+		}
+		fmt.Printf("Requested WebSub subscription to %s at %s\n", sub.TopicUrl, sub.HubUrl)
+	}
+	return nil
+}
+
+// handleWebSubVerify answers a hub confirming, or refusing, a subscription
+// gator requested.
+func (srv *server) handleWebSubVerify(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sub, ok := srv.webSubSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("hub.topic") != sub.TopicUrl {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			http.Error(w, "missing hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		leaseDuration := time.Duration(lease) * time.Second
+		err = srv.store.ActivateWebSubSubscription(ctx, database.ActivateWebSubSubscriptionParams{
+			ID:             sub.ID,
+			LeaseExpiresAt: sql.NullTime{Time: now.Add(leaseDuration), Valid: true},
+			RenewAt:        sql.NullTime{Time: now.Add(leaseDuration * websubRenewPercent / 100), Valid: true},
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to activate subscription: %s", err), http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("WebSub subscription to %s active for %s\n", sub.TopicUrl, time.Duration(lease)*time.Second)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, query.Get("hub.challenge"))
+	case "denied":
+		if err := srv.store.DenyWebSubSubscription(ctx, sub.ID); err != nil {
+			http.Error(w, fmt.Sprintf("failed to record denial: %s", err), http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("WebSub subscription to %s denied: %s\n", sub.TopicUrl, query.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+	default:
+		// gator never unsubscribes, so any other request is not ours.
+		http.NotFound(w, r)
+	}
+}
+
+// handleWebSubPush stores the posts a hub delivers. Deliveries with a
+// missing or wrong signature are acknowledged but ignored, as the WebSub
+// specification requires.
+func (srv *server) handleWebSubPush(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sub, ok := srv.webSubSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, srv.fetcher.MaxBodyBytes()+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read body: %s", err), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > srv.fetcher.MaxBodyBytes() {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !websub.VerifySignature(r.Header.Get("X-Hub-Signature"), sub.Secret, body) {
+		fmt.Printf("ignoring WebSub delivery for %s with an invalid signature\n", sub.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := srv.store.GetFeedByID(ctx, sub.FeedID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get feed: %s", err), http.StatusInternalServerError)
+		return
+	}
+	parsed, err := srv.fetcher.ParseFeed(body, r.Header.Get("Content-Type"), feed.Url)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse feed: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := rss.SavePosts(ctx, srv.store, feed.ID, parsed); err != nil {
+		http.Error(w, fmt.Sprintf("failed to save posts: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("Received %d items for %s from its WebSub hub\n", len(parsed.Channel.Item), feed.Name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (srv *server) webSubSubscription(w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	sub, err := srv.store.GetWebSubSubscription(r.Context(), id)
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
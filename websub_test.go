@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jasonwashburn/gator/internal/database"
+	"github.com/jasonwashburn/gator/internal/rss"
+)
+
+const testTopic = "https://example.com/feed"
+
+// newTestWebSub serves the WebSub callbacks from an in-memory store holding
+// one pending subscription to testTopic.
+func newTestWebSub(t *testing.T) (*httptest.Server, *memStore, database.WebsubSubscription) {
+	t.Helper()
+	fetcher, err := rss.NewFetcher(rss.FetcherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newMemStore()
+	feed := database.Feed{ID: uuid.New(), Name: "Blog", Url: testTopic}
+	store.feeds = append(store.feeds, feed)
+	sub, _ := store.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID:       uuid.New(),
+		FeedID:   feed.ID,
+		HubUrl:   "https://hub.example.com/",
+		TopicUrl: testTopic,
+		Secret:   "s3cret",
+	})
+
+	srv := &server{store: store, fetcher: fetcher}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{id}", srv.handleWebSubVerify)
+	mux.HandleFunc("POST /websub/{id}", srv.handleWebSubPush)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, store, sub
+}
+
+func verify(t *testing.T, ts *httptest.Server, id string, query url.Values) (int, string) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/websub/" + id + "?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestWebSubVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		query     url.Values
+		status    int
+		body      string
+		wantState string
+	}{
+		{
+			name:      "subscribe",
+			query:     url.Values{"hub.mode": {"subscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"abc123"}, "hub.lease_seconds": {"3600"}},
+			status:    http.StatusOK,
+			body:      "abc123",
+			wantState: "active",
+		},
+		{
+			name:      "missing lease",
+			query:     url.Values{"hub.mode": {"subscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"abc123"}},
+			status:    http.StatusBadRequest,
+			wantState: "pending",
+		},
+		{
+			name:      "wrong topic",
+			query:     url.Values{"hub.mode": {"subscribe"}, "hub.topic": {"https://example.com/other"}, "hub.challenge": {"abc123"}, "hub.lease_seconds": {"3600"}},
+			status:    http.StatusNotFound,
+			wantState: "pending",
+		},
+		{
+			name:      "unsubscribe",
+			query:     url.Values{"hub.mode": {"unsubscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"abc123"}},
+			status:    http.StatusNotFound,
+			wantState: "pending",
+		},
+		{
+			name:      "denied",
+			query:     url.Values{"hub.mode": {"denied"}, "hub.topic": {testTopic}, "hub.reason": {"not allowed"}},
+			status:    http.StatusOK,
+			wantState: "denied",
+		},
+		{
+			name:      "unknown subscription",
+			id:        uuid.NewString(),
+			query:     url.Values{"hub.mode": {"subscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"abc123"}, "hub.lease_seconds": {"3600"}},
+			status:    http.StatusNotFound,
+			wantState: "pending",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, store, sub := newTestWebSub(t)
+			id := tt.id
+			if id == "" {
+				id = sub.ID.String()
+			}
+			status, body := verify(t, ts, id, tt.query)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if tt.body != "" && body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if state := store.subs[sub.ID].State; state != tt.wantState {
+				t.Errorf("state = %q, want %q", state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestWebSubVerifyRenewsWithinLease(t *testing.T) {
+	for _, lease := range []time.Duration{time.Hour, 10 * 24 * time.Hour} {
+		ts, store, sub := newTestWebSub(t)
+		start := time.Now()
+		status, _ := verify(t, ts, sub.ID.String(), url.Values{
+			"hub.mode":          {"subscribe"},
+			"hub.topic":         {testTopic},
+			"hub.challenge":     {"abc123"},
+			"hub.lease_seconds": {strconv.Itoa(int(lease.Seconds()))},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("lease %s: status = %d, want 200", lease, status)
+		}
+
+		got := store.subs[sub.ID]
+		if !got.RenewAt.Valid || !got.LeaseExpiresAt.Valid {
+			t.Fatalf("lease %s: renew_at and lease_expires_at must be set", lease)
+		}
+		if !got.RenewAt.Time.Before(got.LeaseExpiresAt.Time) {
+			t.Errorf("lease %s: renewed at %s, after the lease expires at %s", lease, got.RenewAt.Time, got.LeaseExpiresAt.Time)
+		}
+		if renewIn := got.RenewAt.Time.Sub(start); renewIn < lease/2 {
+			t.Errorf("lease %s: renewed after %s, less than half the lease", lease, renewIn)
+		}
+	}
+}
+
+func signBody(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const testPush = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><title>Hello</title><link>https://example.com/hello</link><guid>hello</guid></item>
+</channel></rss>`
+
+func TestWebSubPush(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		signature string
+		status    int
+		saved     int
+	}{
+		{"signed", "", signBody("s3cret", testPush), http.StatusAccepted, 1},
+		{"bad signature", "", signBody("other", testPush), http.StatusAccepted, 0},
+		{"unsigned", "", "", http.StatusAccepted, 0},
+		{"unknown subscription", uuid.NewString(), signBody("s3cret", testPush), http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, store, sub := newTestWebSub(t)
+			id := tt.id
+			if id == "" {
+				id = sub.ID.String()
+			}
+			req, err := http.NewRequest("POST", ts.URL+"/websub/"+id, strings.NewReader(testPush))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/rss+xml")
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if len(store.saved) != tt.saved {
+				t.Errorf("saved %d posts, want %d", len(store.saved), tt.saved)
+			}
+		})
+	}
+}
+
+func TestSubscribeWebSubStopsWithContext(t *testing.T) {
+	srv := &server{store: newMemStore()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.subscribeWebSub(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribeWebSub did not return after its context was canceled")
+	}
+}